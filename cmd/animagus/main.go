@@ -2,44 +2,105 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/xxuejie/animagus/pkg/generic"
 	"github.com/xxuejie/animagus/pkg/indexer"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
 	"google.golang.org/grpc"
 )
 
-var astFile = flag.String("astFile", "./ast.bin", "AST file to load")
+var astFile = flag.String("astFile", "./ast.bin", "AST files to load separated by commas, each one is a program named after the file, or name=path to name the program explicitly")
+var storeType = flag.String("store", "redis", "Index store to use, either redis or bolt")
 var redisUrl = flag.String("redisUrl", "redis://127.0.0.1:6379", "Redis URL")
+var boltFile = flag.String("boltFile", "./animagus.db", "Database file for bolt store")
+var sourceType = flag.String("source", "graphql", "Block source to use, one of graphql, rpc or file")
+var rpcUrl = flag.String("rpcUrl", "http://127.0.0.1:8114", "CKB RPC URL")
+var blockFile = flag.String("blockFile", "./blocks.jsonl", "Block dump file or directory for file source")
 var graphqlUrl = flag.String("graphqlUrl", "http://127.0.0.1:3001/graphql", "Redis URL")
+var prefetch = flag.Uint64("prefetch", 16, "Number of blocks to fetch concurrently when syncing")
+var bulkSyncDistance = flag.Uint64("bulkSyncDistance", 0, "Blocks at least this far behind chain tip are indexed in bulk without revert logs, 0 disables bulk sync")
+var bulkSyncBatch = flag.Uint64("bulkSyncBatch", 500, "Number of blocks indexed per commit in bulk sync")
+var reorgDepth = flag.Uint64("reorgDepth", 0, "Number of latest blocks keeping revert logs, deeper reorgs result in an error, 0 keeps revert logs of all blocks")
+var startBlock = flag.Uint64("startBlock", 0, "Block to start indexing from when the index is empty, cells live before it are bootstrapped from the block source")
 var grpcListenAddress = flag.String("grpcListenAddress", ":4000", "GRPC Listen Address")
+var metricsListenAddress = flag.String("metricsListenAddress", ":4001", "Listen address of HTTP server for Prometheus metrics at /metrics, empty disables metrics")
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "sign":
+			runSign(os.Args[2:])
+			return
+		case "import-key":
+			runImportKey(os.Args[2:])
+			return
+		case "status":
+			runStatus(os.Args[2:])
+			return
+		}
+	}
 	flag.Parse()
 
-	astContent, err := ioutil.ReadFile(*astFile)
+	programs, err := parsePrograms(*astFile)
 	if err != nil {
 		log.Fatal(err)
 	}
-	redisPool := &redis.Pool{
-		MaxIdle:     2,
-		IdleTimeout: 60 * time.Second,
-		Dial:        func() (redis.Conn, error) { return redis.DialURL(*redisUrl) },
-	}
-	// TODO: multiple call support later
-	i, err := indexer.NewIndexer(astContent, redisPool, *graphqlUrl)
+	s, err := openStore()
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer s.Close()
 
-	genericServer, err := generic.NewServer(astContent, redisPool, *graphqlUrl)
+	deleted, err := indexer.DeleteLegacyKeys(s)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if deleted {
+		log.Print("Deleted index of a previous animagus version, programs are indexed again from scratch")
+	}
+
+	genericServer := generic.NewServer()
+	indexerPrograms := make([]*indexer.Program, len(programs))
+	for j, program := range programs {
+		astContent, err := ioutil.ReadFile(program.path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Each program keeps its own indexes, including stored live cells.
+		p, err := indexer.NewProgram(program.name, astContent,
+			store.NewPrefixStore(s, indexer.ProgramKeyPrefix(program.name)),
+			openSource, configureIndexer)
+		if err != nil {
+			log.Fatalf("Error loading program %s: %s", program.name, err)
+		}
+		activeContent, activeStore, activeSource := p.Active()
+		err = genericServer.AddProgram(program.name, activeContent, activeStore, activeSource)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = genericServer.SetProgress(program.name, p.Progress)
+		if err != nil {
+			log.Fatal(err)
+		}
+		name := program.name
+		p.OnSwitch(func(astContent []byte, s store.Store, src source.Source) error {
+			return genericServer.ReplaceProgram(name, astContent, s, src)
+		})
+		indexerPrograms[j] = p
+	}
+
 	lis, err := net.Listen("tcp", *grpcListenAddress)
 	if err != nil {
 		log.Fatal(err)
@@ -47,9 +108,92 @@ func main() {
 	grpcServer := grpc.NewServer()
 	generic.RegisterGenericServiceServer(grpcServer, genericServer)
 
-	go func() {
-		log.Fatal(i.Run())
-	}()
+	if len(*metricsListenAddress) > 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		go func() {
+			log.Fatal(http.ListenAndServe(*metricsListenAddress, mux))
+		}()
+	}
+
+	for _, p := range indexerPrograms {
+		go func(p *indexer.Program) {
+			log.Fatal(p.Run())
+		}(p)
+	}
 
 	grpcServer.Serve(lis)
 }
+
+type programFile struct {
+	name string
+	path string
+}
+
+func parsePrograms(value string) ([]programFile, error) {
+	var programs []programFile
+	names := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		program := programFile{path: item}
+		if index := strings.Index(item, "="); index != -1 {
+			program.name = item[:index]
+			program.path = item[index+1:]
+		} else {
+			program.name = strings.TrimSuffix(filepath.Base(item), filepath.Ext(item))
+		}
+		if len(program.name) == 0 || strings.ContainsAny(program.name, "/:") {
+			return nil, fmt.Errorf("Invalid program name for AST file %s!", item)
+		}
+		if names[program.name] {
+			return nil, fmt.Errorf("Duplicate program name: %s", program.name)
+		}
+		names[program.name] = true
+		programs = append(programs, program)
+	}
+	return programs, nil
+}
+
+func openStore() (store.Store, error) {
+	switch *storeType {
+	case "redis":
+		redisPool := &redis.Pool{
+			MaxIdle:     2,
+			IdleTimeout: 60 * time.Second,
+			Dial:        func() (redis.Conn, error) { return redis.DialURL(*redisUrl) },
+		}
+		return store.NewRedisStore(redisPool), nil
+	case "bolt":
+		return store.NewBoltStore(*boltFile)
+	}
+	return nil, fmt.Errorf("Invalid store type: %s", *storeType)
+}
+
+func configureIndexer(i *indexer.Indexer, src source.Source) {
+	if fileSource, ok := src.(*source.FileSource); ok {
+		i.SetStartBlock(fileSource.FirstBlockNumber())
+	} else {
+		if *startBlock > 0 {
+			i.SetStartBlock(*startBlock)
+			i.SetBootstrap(true)
+		}
+		// Replaying files relies on blocks being requested in order.
+		i.SetPrefetch(*prefetch)
+	}
+	if _, ok := src.(source.TipSource); !ok && *bulkSyncDistance > 0 {
+		log.Printf("Source %s does not report chain tip, bulk sync is disabled!", *sourceType)
+	}
+	i.SetBulkSync(*bulkSyncDistance, *bulkSyncBatch)
+	i.SetReorgDepth(*reorgDepth)
+}
+
+func openSource(s store.Store) (source.Source, error) {
+	switch *sourceType {
+	case "graphql":
+		return source.NewGraphqlSource(*graphqlUrl)
+	case "rpc":
+		return source.NewRpcSource(*rpcUrl, s)
+	case "file":
+		return source.NewFileSource(*blockFile)
+	}
+	return nil, fmt.Errorf("Invalid source type: %s", *sourceType)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/machinebox/graphql"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/signer"
+)
+
+const passwordEnv = "ANIMAGUS_KEYSTORE_PASSWORD"
+
+func readPassword(passwordFile string) (string, error) {
+	if passwordFile != "" {
+		data, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	password, ok := os.LookupEnv(passwordEnv)
+	if !ok {
+		return "", fmt.Errorf("Keystore password must be provided via -passwordFile or %s!", passwordEnv)
+	}
+	return password, nil
+}
+
+type getCellsResponse struct {
+	GetCells []*rpctypes.OutPoint
+}
+
+// readInputLocks reads lock scripts of transaction inputs from a JSON file
+// containing one lock script per input, in the same order as inputs. Locks
+// are not included in the transaction itself but are required for grouping,
+// they can be assembled from SIGNING_ENTRIES returned with the transaction.
+func readInputLocks(locksFile string, tx rpctypes.Transaction) ([]rpctypes.Script, error) {
+	data, err := ioutil.ReadFile(locksFile)
+	if err != nil {
+		return nil, err
+	}
+	var locks []rpctypes.Script
+	err = json.Unmarshal(data, &locks)
+	if err != nil {
+		return nil, err
+	}
+	if len(locks) != len(tx.Inputs) {
+		return nil, fmt.Errorf("Expected %d input locks, got %d!", len(tx.Inputs), len(locks))
+	}
+	return locks, nil
+}
+
+// fetchInputLocks fetches lock scripts of transaction inputs from
+// ckb-graphql-server, inputs must be live cells.
+func fetchInputLocks(graphqlUrl string, tx rpctypes.Transaction) ([]rpctypes.Script, error) {
+	if len(tx.Inputs) == 0 {
+		return []rpctypes.Script{}, nil
+	}
+	pieces := make([]string, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		pieces[i] = fmt.Sprintf("{txHash: \"0x%x\", index: \"0x%x\"}",
+			input.PreviousOutput.TxHash[:], uint32(input.PreviousOutput.Index))
+	}
+	req := graphql.NewRequest(fmt.Sprintf(`
+query {
+  getCells(outPoints: [%s], skipMissing: true) {
+    cell {
+      lock {
+        code_hash
+        hash_type
+        args
+      }
+    }
+    tx_hash
+    index
+  }
+}
+`, strings.Join(pieces, ",")))
+	var response getCellsResponse
+	err := graphql.NewClient(graphqlUrl).Run(context.Background(), req, &response)
+	if err != nil {
+		return nil, err
+	}
+	locks := make([]rpctypes.Script, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		found := false
+		for _, cell := range response.GetCells {
+			if cell.TxHash == input.PreviousOutput.TxHash &&
+				cell.Index == input.PreviousOutput.Index &&
+				cell.GraphqlCell != nil {
+				locks[i] = cell.GraphqlCell.Lock
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Cell for input %d cannot be found, it might be spent already!", i)
+		}
+	}
+	return locks, nil
+}
+
+func runSign(args []string) {
+	flags := flag.NewFlagSet("sign", flag.ExitOnError)
+	keystoreDir := flags.String("keystore", "./keystore", "Keystore directory")
+	passwordFile := flags.String("passwordFile", "", "File containing keystore password, "+passwordEnv+" is used when not provided")
+	txFile := flags.String("tx", "-", "Transaction JSON file generated by SERIALIZE_TO_JSON, - for stdin")
+	locksFile := flags.String("locks", "", "JSON file containing lock script of each input")
+	graphqlUrl := flags.String("graphqlUrl", "", "GraphQL URL used to fetch input locks when -locks is not provided")
+	flags.Parse(args)
+
+	if *locksFile == "" && *graphqlUrl == "" {
+		log.Fatal("Input locks must be provided via -locks or fetched via -graphqlUrl!")
+	}
+	password, err := readPassword(*passwordFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keys, failures, err := signer.NewKeystore(*keystoreDir).Unlock(password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for name, err := range failures {
+		log.Printf("Skipping key %s: %s", name, err)
+	}
+	if len(keys) == 0 {
+		log.Fatal("No key in keystore can be unlocked!")
+	}
+	var data []byte
+	if *txFile == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(*txFile)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	var tx rpctypes.Transaction
+	err = json.Unmarshal(data, &tx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var locks []rpctypes.Script
+	if *locksFile != "" {
+		locks, err = readInputLocks(*locksFile, tx)
+	} else {
+		locks, err = fetchInputLocks(*graphqlUrl, tx)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	signed, err := signer.NewSigner(keys).SignTransaction(&tx, locks)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if signed == 0 {
+		log.Fatal("No input can be signed by keys in keystore!")
+	}
+	log.Printf("Signed %d input groups", signed)
+	output, err := json.MarshalIndent(tx, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(output))
+}
+
+func runImportKey(args []string) {
+	flags := flag.NewFlagSet("import-key", flag.ExitOnError)
+	keystoreDir := flags.String("keystore", "./keystore", "Keystore directory")
+	passwordFile := flags.String("passwordFile", "", "File containing keystore password, "+passwordEnv+" is used when not provided")
+	keyFile := flags.String("keyFile", "", "File containing hex encoded private key, a new key is generated when not provided")
+	flags.Parse(args)
+
+	password, err := readPassword(*passwordFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var key *signer.Key
+	if *keyFile != "" {
+		data, err := ioutil.ReadFile(*keyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		key, err = signer.KeyFromBytes(b)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		key, err = signer.NewKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	path, err := signer.NewKeystore(*keystoreDir).Import(key, password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lockArg, err := key.Blake160()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Imported key with lock arg 0x%x to %s\n", lockArg, path)
+}
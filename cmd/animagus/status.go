@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/xxuejie/animagus/pkg/generic"
+	"google.golang.org/grpc"
+)
+
+func runStatus(args []string) {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	grpcAddress := flags.String("grpcAddress", "127.0.0.1:4000", "GRPC address of running animagus")
+	program := flags.String("program", "", "Program to report, can be omitted when only one program is loaded")
+	flags.Parse(args)
+
+	conn, err := grpc.Dial(*grpcAddress, grpc.WithInsecure())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status, err := generic.NewGenericServiceClient(conn).Status(ctx, &generic.StatusParams{
+		Program: *program,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Program: %s\n", status.GetProgram())
+	fmt.Printf("AST hash: 0x%x\n", status.GetAstHash())
+	if len(status.GetIndexedBlockHash()) > 0 {
+		fmt.Printf("Indexed block: %d, hash: 0x%x\n", status.GetIndexedBlockNumber(), status.GetIndexedBlockHash())
+	} else {
+		fmt.Println("Indexed block: none")
+	}
+	if status.GetTipKnown() {
+		fmt.Printf("Chain tip: %d\n", status.GetTipBlockNumber())
+		fmt.Printf("Lag: %d blocks\n", status.GetLag())
+	} else {
+		fmt.Println("Chain tip: unknown")
+	}
+	fmt.Printf("Blocks per second: %.2f\n", status.GetBlocksPerSecond())
+	if status.GetLastReorgTime() > 0 {
+		fmt.Printf("Last reorg: block %d at %s\n", status.GetLastReorgBlockNumber(),
+			time.Unix(status.GetLastReorgTime(), 0).Format(time.RFC3339))
+	} else {
+		fmt.Println("Last reorg: none")
+	}
+}
@@ -64,19 +64,31 @@ func string_value(s string) *ast.Value {
 	}
 }
 
-func main() {
+func not(value *ast.Value) *ast.Value {
+	return &ast.Value{
+		T:        ast.Value_NOT,
+		Children: []*ast.Value{value},
+	}
+}
+
+func daoFilter(depositTest func(*ast.Value) *ast.Value) *ast.Value {
 	script := fetch_field(ast.Value_GET_TYPE, arg(0))
 	code_hash_test := equal(
 		fetch_field(ast.Value_GET_CODE_HASH, script),
 		bytes_value(DaoTypeHash))
 	type_test := equal(arg(1), string_value("insert"))
 	index_test := equal(arg(2), string_value("index"))
+	// Deposit cells keep 8 zero bytes as cell data, while withdrawing cells
+	// store the block number of the original deposit there.
+	data_test := depositTest(equal(
+		fetch_field(ast.Value_GET_DATA, arg(0)),
+		bytes_value([]byte{0, 0, 0, 0, 0, 0, 0, 0})))
 
-	tests := and(code_hash_test, type_test, index_test)
+	tests := and(code_hash_test, type_test, index_test, data_test)
 
 	out_point := fetch_field(ast.Value_GET_OUT_POINT, arg(0))
 
-	filter := &ast.Value{
+	return &ast.Value{
 		T: ast.Value_COND,
 		Children: []*ast.Value{
 			tests,
@@ -86,12 +98,20 @@ func main() {
 			},
 		},
 	}
+}
 
+func main() {
 	root := &ast.Root{
 		Streams: []*ast.Stream{
 			&ast.Stream{
-				Name:   "nervosdao_deposits",
-				Filter: filter,
+				Name: "nervosdao_deposits",
+				Filter: daoFilter(func(value *ast.Value) *ast.Value {
+					return value
+				}),
+			},
+			&ast.Stream{
+				Name:   "nervosdao_withdraws",
+				Filter: daoFilter(not),
 			},
 		},
 	}
@@ -8,13 +8,14 @@ def bin_to_hex(bin)
 end
 
 def main
+  stream = ARGV[0] || "nervosdao_deposits"
   stub = Generic::GenericService::Stub.new("127.0.0.1:4000", :this_channel_is_insecure)
   request = Generic::GenericParams.new(
-    name: "nervosdao_deposits"
+    name: stream
   )
   response = stub.stream(request)
   response.each do |r|
-    puts "New NervosDAO deposit at tx hash: #{bin_to_hex(r.children[0].raw)}, index: #{r.children[1].u}"
+    puts "New #{stream} event at tx hash: #{bin_to_hex(r.children[0].raw)}, index: #{r.children[1].u}"
   end
 end
 
@@ -16,6 +16,9 @@ var (
 	// Devnet
 	SecpCellDep = []byte{0xac, 0xe5, 0xea, 0x83, 0xc4, 0x78, 0xbb, 0x86, 0x6e, 0xdf, 0x12, 0x2f, 0xf8, 0x62, 0x08, 0x57, 0x89, 0x15, 0x8f, 0x5c, 0xbf, 0xf1, 0x55, 0xb7, 0xbb, 0x5f, 0x13, 0x05, 0x85, 0x55, 0xb7, 0x08}
 
+	// Mainnet, use the deployment of anyone-can-pay lock on your own chain
+	AcpCellDep = []byte{0x41, 0x53, 0xa2, 0x01, 0x49, 0x52, 0xd7, 0xca, 0xc4, 0x5f, 0x28, 0x5c, 0xe9, 0xa7, 0xc5, 0xc0, 0xc0, 0xe1, 0xb2, 0x1f, 0x2d, 0x37, 0x8b, 0x82, 0xac, 0x14, 0x33, 0xcb, 0x11, 0xc2, 0x5c, 0x4d}
+
 	UdtCodeHash = []byte{0x57, 0xdd, 0x00, 0x67, 0x81, 0x4d, 0xab, 0x35, 0x6e, 0x05, 0xc6, 0xde, 0xf0, 0xd0, 0x94, 0xbb, 0x79, 0x77, 0x67, 0x11, 0xe6, 0x8f, 0xfd, 0xfa, 0xd2, 0xdf, 0x6a, 0x7f, 0x87, 0x7f, 0x7d, 0xb6}
 )
 
@@ -132,6 +135,15 @@ func assembleSecpLock(paramIndex uint64) *ast.Value {
 	}
 }
 
+func decodeAddress(paramIndex uint64) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_DECODE_ADDRESS,
+		Children: []*ast.Value{
+			param(paramIndex),
+		},
+	}
+}
+
 func assembleSecpCellDep() *ast.Value {
 	return &ast.Value{
 		T: ast.Value_CELL_DEP,
@@ -148,73 +160,62 @@ func assembleSecpCellDep() *ast.Value {
 	}
 }
 
-func assembleUdtType(paramIndex uint64) *ast.Value {
-	return &ast.Value{
-		T: ast.Value_SCRIPT,
-		Children: []*ast.Value{
-			bytes_value(UdtCodeHash),
-			uint_value(0),
-			param(paramIndex),
-		},
-	}
+func isAcpCell(argIndex uint64, paramIndex uint64) *ast.Value {
+	lock := fetch_field(ast.Value_GET_LOCK, arg(argIndex))
+
+	acp_test := fetch_field(ast.Value_IS_ACP_LOCK, lock)
+
+	args_test := equal(fetch_field(ast.Value_GET_ARGS, lock), param(paramIndex))
+
+	return and(acp_test, args_test)
 }
 
-func adjustFee(tx *ast.Value) *ast.Value {
-	length := &ast.Value{
-		T: ast.Value_LEN,
-		Children: []*ast.Value{
-			&ast.Value{
-				T:        ast.Value_SERIALIZE_TO_CORE,
-				Children: []*ast.Value{tx},
-			},
-		},
-	}
-	fee := &ast.Value{
-		T: ast.Value_MULTIPLY,
+func assembleAcpCellDep() *ast.Value {
+	return &ast.Value{
+		T: ast.Value_CELL_DEP,
 		Children: []*ast.Value{
-			// Adding extra bytes here to set aside for signatures
 			&ast.Value{
-				T: ast.Value_ADD,
+				T: ast.Value_OUT_POINT,
 				Children: []*ast.Value{
-					length,
-					uint_value(100),
+					bytes_value(AcpCellDep),
+					uint_value(0),
 				},
 			},
 			uint_value(1),
 		},
 	}
-	changeCell := tx.GetChildren()[1].GetChildren()[1]
-	adjustedChangeCell := &ast.Value{
-		T: ast.Value_CELL,
+}
+
+func assembleUdtType(paramIndex uint64) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_SCRIPT,
 		Children: []*ast.Value{
-			&ast.Value{
-				T: ast.Value_SUBTRACT,
-				Children: []*ast.Value{
-					changeCell.GetChildren()[0],
-					fee,
-				},
-			},
-			changeCell.GetChildren()[1],
-			changeCell.GetChildren()[2],
-			changeCell.GetChildren()[3],
+			bytes_value(UdtCodeHash),
+			uint_value(0),
+			param(paramIndex),
 		},
 	}
+}
+
+// Fee is paid by the change cell at the fee rate(shannons/KB) in param 4.
+func deductFee(transaction *ast.Value, changeIndex uint64) *ast.Value {
 	return &ast.Value{
-		T: ast.Value_TRANSACTION,
+		T: ast.Value_DEDUCT_FEE,
 		Children: []*ast.Value{
-			tx.GetChildren()[0],
-			&ast.Value{
-				T: ast.Value_LIST,
-				Children: []*ast.Value{
-					tx.GetChildren()[1].GetChildren()[0],
-					adjustedChangeCell,
-				},
-			},
-			tx.GetChildren()[2],
+			transaction,
+			param(4),
+			uint_value(changeIndex),
 		},
 	}
 }
 
+func withSigningEntries(transaction *ast.Value) *ast.Value {
+	return &ast.Value{
+		T:        ast.Value_SIGNING_REQUEST,
+		Children: []*ast.Value{transaction},
+	}
+}
+
 func main() {
 	typeCells := &ast.Value{
 		T: ast.Value_QUERY_CELLS,
@@ -330,19 +331,35 @@ func main() {
 		},
 	}
 
+	// Transfer cell only gets the minimal capacity required to hold itself
+	transferCapacities := &ast.Value{
+		T: ast.Value_OCCUPIED_CAPACITY,
+		Children: []*ast.Value{
+			&ast.Value{
+				T: ast.Value_CELL,
+				Children: []*ast.Value{
+					uint_value(0),
+					decodeAddress(2),
+					assembleUdtType(0),
+					transferTokens,
+				},
+			},
+		},
+	}
+
 	changeCapacities := &ast.Value{
 		T: ast.Value_SUBTRACT,
 		Children: []*ast.Value{
 			totalCapacities,
-			uint_value(142 * 100000000),
+			transferCapacities,
 		},
 	}
 
 	transferCell := &ast.Value{
 		T: ast.Value_CELL,
 		Children: []*ast.Value{
-			uint_value(142 * 100000000),
-			assembleSecpLock(2),
+			transferCapacities,
+			decodeAddress(2),
 			assembleUdtType(0),
 			transferTokens,
 		},
@@ -361,6 +378,9 @@ func main() {
 	// TODO: witness support
 	transaction := &ast.Value{
 		T: ast.Value_TRANSACTION,
+		Primitive: &ast.Value_U{
+			U: ast.CheckConsensus,
+		},
 		Children: []*ast.Value{
 			cells,
 			&ast.Value{
@@ -385,13 +405,95 @@ func main() {
 			},
 		},
 	}
-	transaction = adjustFee(transaction)
+	transaction = deductFee(transaction, 1)
+
+	// Instead of creating a new cell for the receiver, tokens can be paid to
+	// an existing anyone-can-pay cell identified by lock args in param 2. The
+	// cell is consumed and recreated with more tokens, so no extra capacity
+	// is needed.
+	acpCell := &ast.Value{
+		T: ast.Value_INDEX,
+		Children: []*ast.Value{
+			uint_value(0),
+			&ast.Value{
+				T: ast.Value_QUERY_CELLS,
+				Children: []*ast.Value{
+					and(isAcpCell(0, 2), isSimpleUdtCell(0, 0)),
+				},
+			},
+		},
+	}
 
-	serializedTransaction := &ast.Value{
-		T:        ast.Value_SERIALIZE_TO_JSON,
-		Children: []*ast.Value{transaction},
+	// The sender's cells and the anyone-can-pay cell are picked from all
+	// cells of the UDT, so they can be consumed in the same list of inputs.
+	acpInputs := &ast.Value{
+		T: ast.Value_FILTER,
+		Children: []*ast.Value{
+			&ast.Value{
+				T: ast.Value_OR,
+				Children: []*ast.Value{
+					isDefaultSecpCell(0),
+					equal(arg(0), acpCell),
+				},
+			},
+			&ast.Value{
+				T: ast.Value_QUERY_CELLS,
+				Children: []*ast.Value{
+					isSimpleUdtCell(0, 0),
+				},
+			},
+		},
+	}
+
+	acpChangeCell := &ast.Value{
+		T: ast.Value_CELL,
+		Children: []*ast.Value{
+			totalCapacities,
+			assembleSecpLock(1),
+			assembleUdtType(0),
+			changeTokens,
+		},
 	}
 
+	acpTransaction := &ast.Value{
+		T: ast.Value_TRANSACTION,
+		Primitive: &ast.Value_U{
+			U: ast.CheckConsensus,
+		},
+		Children: []*ast.Value{
+			acpInputs,
+			&ast.Value{
+				T: ast.Value_LIST,
+				Children: []*ast.Value{
+					&ast.Value{
+						T: ast.Value_ACP_TOP_UP,
+						Children: []*ast.Value{
+							acpCell,
+							uint_value(0),
+							param(3),
+						},
+					},
+					acpChangeCell,
+				},
+			},
+			&ast.Value{
+				T: ast.Value_LIST,
+				Children: []*ast.Value{
+					assembleSecpCellDep(),
+					assembleAcpCellDep(),
+					&ast.Value{
+						T: ast.Value_INDEX,
+						Children: []*ast.Value{
+							uint_value(0),
+							typeCells,
+						},
+					},
+				},
+			},
+		},
+	}
+	acpTransaction = deductFee(acpTransaction, 1)
+
 	root := &ast.Root{
 		Calls: []*ast.Call{
 			&ast.Call{
@@ -404,7 +506,11 @@ func main() {
 			},
 			&ast.Call{
 				Name:   "transfer",
-				Result: serializedTransaction,
+				Result: withSigningEntries(transaction),
+			},
+			&ast.Call{
+				Name:   "transfer_acp",
+				Result: withSigningEntries(acpTransaction),
 			},
 		},
 	}
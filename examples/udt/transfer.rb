@@ -4,8 +4,8 @@ require "json"
 require "grpc"
 require "generic_services_pb"
 
-if ARGV.length != 4
-  puts "Usage: ruby transfer.rb <udt type arg> <from lock arg> <to lock arg> <amount>"
+if ARGV.length != 4 && ARGV.length != 5
+  puts "Usage: ruby transfer.rb <udt type arg> <from lock arg> <to address> <amount> [fee rate]"
   exit 1
 end
 
@@ -14,6 +14,20 @@ def hex_to_bin(hex)
   [hex].pack("H*")
 end
 
+def bin_to_hex(bin)
+  "0x#{bin.unpack1('H*')}"
+end
+
+HASH_TYPES = ["data", "type", "data1"]
+
+def script_to_json(script)
+  {
+    code_hash: bin_to_hex(script.children[0].raw),
+    hash_type: HASH_TYPES[script.children[1].u],
+    args: bin_to_hex(script.children[2].raw),
+  }
+end
+
 def unpack_amount(data)
   values = data.unpack("Q<Q<")
   (values[1] << 64) | values[0]
@@ -35,16 +49,32 @@ def main
       ),
       Ast::Value.new(
         t: Ast::Value::Type::BYTES,
-        raw: hex_to_bin(ARGV[2])
+        raw: ARGV[2]
       ),
       Ast::Value.new(
         t: Ast::Value::Type::UINT64,
         u: ARGV[3].to_i
       ),
+      Ast::Value.new(
+        t: Ast::Value::Type::UINT64,
+        u: (ARGV[4] || 1000).to_i
+      ),
     ]
   )
   response = stub.call(request)
-  puts JSON.pretty_generate(JSON.parse(response.raw))
+  tx = JSON.parse(response.children[0].raw)
+  puts JSON.pretty_generate(tx)
+  locks = Array.new(tx["inputs"].length)
+  response.children[1].children.each do |entry|
+    lock_args = bin_to_hex(entry.children[0].children[2].raw)
+    indices = entry.children[1].children.map(&:u)
+    indices.each { |i| locks[i] = script_to_json(entry.children[0]) }
+    puts "Sign #{bin_to_hex(entry.children[2].raw)} with lock args #{lock_args} for inputs #{indices}"
+  end
+  File.write("tx.json", JSON.pretty_generate(tx))
+  File.write("locks.json", JSON.pretty_generate(locks))
+  puts "Transaction and input locks are written to tx.json and locks.json, they can be signed offline via:"
+  puts "animagus sign -tx tx.json -locks locks.json"
 end
 
 main
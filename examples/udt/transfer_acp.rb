@@ -0,0 +1,63 @@
+$LOAD_PATH.unshift(File.expand_path(File.join(File.dirname(__FILE__), "..", "..", "ruby")))
+
+require "json"
+require "grpc"
+require "generic_services_pb"
+
+if ARGV.length != 4 && ARGV.length != 5
+  puts "Usage: ruby transfer_acp.rb <udt type arg> <from lock arg> <to acp lock arg> <amount> [fee rate]"
+  exit 1
+end
+
+def hex_to_bin(hex)
+  hex = hex[2..-1] if hex.start_with?("0x")
+  [hex].pack("H*")
+end
+
+def bin_to_hex(bin)
+  "0x#{bin.unpack1('H*')}"
+end
+
+def unpack_amount(data)
+  values = data.unpack("Q<Q<")
+  (values[1] << 64) | values[0]
+end
+
+
+def main
+  stub = Generic::GenericService::Stub.new("127.0.0.1:4000", :this_channel_is_insecure)
+  request = Generic::GenericParams.new(
+    name: "transfer_acp",
+    params: [
+      Ast::Value.new(
+        t: Ast::Value::Type::BYTES,
+        raw: hex_to_bin(ARGV[0])
+      ),
+      Ast::Value.new(
+        t: Ast::Value::Type::BYTES,
+        raw: hex_to_bin(ARGV[1])
+      ),
+      Ast::Value.new(
+        t: Ast::Value::Type::BYTES,
+        raw: hex_to_bin(ARGV[2])
+      ),
+      Ast::Value.new(
+        t: Ast::Value::Type::UINT64,
+        u: ARGV[3].to_i
+      ),
+      Ast::Value.new(
+        t: Ast::Value::Type::UINT64,
+        u: (ARGV[4] || 1000).to_i
+      ),
+    ]
+  )
+  response = stub.call(request)
+  puts JSON.pretty_generate(JSON.parse(response.children[0].raw))
+  response.children[1].children.each do |entry|
+    lock_args = bin_to_hex(entry.children[0].children[2].raw)
+    indices = entry.children[1].children.map(&:u)
+    puts "Sign #{bin_to_hex(entry.children[2].raw)} with lock args #{lock_args} for inputs #{indices}"
+  end
+end
+
+main
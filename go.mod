@@ -4,6 +4,7 @@ go 1.13
 
 require (
 	github.com/awalterschulze/goderive v0.0.0-20190728081913-2613afbe1240
+	github.com/btcsuite/btcd v0.20.1-beta
 	github.com/golang/protobuf v1.3.2
 	github.com/gomodule/redigo v2.0.0+incompatible
 	github.com/kisielk/gotool v1.0.0 // indirect
@@ -11,6 +12,10 @@ require (
 	github.com/matryer/is v1.2.0 // indirect
 	github.com/minio/blake2b-simd v0.0.0-20160723061019-3f5f724cb5b1
 	github.com/pkg/errors v0.8.1 // indirect
+	github.com/prometheus/client_golang v1.3.0
+	github.com/prometheus/client_model v0.1.0
+	go.etcd.io/bbolt v1.3.4
+	golang.org/x/crypto v0.0.0-20191206172530-e9b2fee46413
 	golang.org/x/tools v0.0.0-20191217011448-c39ce2148d8e // indirect
 	google.golang.org/grpc v1.26.0
 )
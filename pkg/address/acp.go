@@ -0,0 +1,110 @@
+package address
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+// AnyoneCanPayArgs holds args of anyone-can-pay lock, minimum amounts are
+// kept as exponents of 10, nil means the minimum is not set.
+type AnyoneCanPayArgs struct {
+	PubkeyHash []byte
+	MinimumCKB *uint8
+	MinimumUDT *uint8
+}
+
+func (a AnyoneCanPayArgs) Serialize() ([]byte, error) {
+	if len(a.PubkeyHash) != 20 {
+		return nil, fmt.Errorf("Pubkey hash should be exactly 20 bytes!")
+	}
+	args := append([]byte{}, a.PubkeyHash...)
+	if a.MinimumCKB != nil {
+		args = append(args, *a.MinimumCKB)
+	}
+	if a.MinimumUDT != nil {
+		if a.MinimumCKB == nil {
+			return nil, fmt.Errorf("Minimum UDT amount requires minimum CKB amount to be set!")
+		}
+		args = append(args, *a.MinimumUDT)
+	}
+	return args, nil
+}
+
+func anyoneCanPayTypeHash(prefix string) (rpctypes.Hash, error) {
+	switch prefix {
+	case Mainnet:
+		return MainnetAnyoneCanPayTypeHash, nil
+	case Testnet:
+		return TestnetAnyoneCanPayTypeHash, nil
+	}
+	return rpctypes.Hash{}, fmt.Errorf("Invalid address prefix: %s", prefix)
+}
+
+func AnyoneCanPayLock(prefix string, args AnyoneCanPayArgs) (rpctypes.Script, error) {
+	codeHash, err := anyoneCanPayTypeHash(prefix)
+	if err != nil {
+		return rpctypes.Script{}, err
+	}
+	serializedArgs, err := args.Serialize()
+	if err != nil {
+		return rpctypes.Script{}, err
+	}
+	return rpctypes.Script{
+		CodeHash: codeHash,
+		HashType: rpctypes.Type,
+		Args:     serializedArgs,
+	}, nil
+}
+
+func IsAnyoneCanPayLock(script rpctypes.Script) bool {
+	return (script.CodeHash == MainnetAnyoneCanPayTypeHash ||
+		script.CodeHash == TestnetAnyoneCanPayTypeHash) &&
+		script.HashType == rpctypes.Type &&
+		len(script.Args) >= 20 && len(script.Args) <= 22
+}
+
+func ParseAnyoneCanPayArgs(script rpctypes.Script) (AnyoneCanPayArgs, error) {
+	var args AnyoneCanPayArgs
+	if !IsAnyoneCanPayLock(script) {
+		return args, fmt.Errorf("Script is not an anyone-can-pay lock!")
+	}
+	args.PubkeyHash = script.Args[:20]
+	if len(script.Args) > 20 {
+		minimum := script.Args[20]
+		args.MinimumCKB = &minimum
+	}
+	if len(script.Args) > 21 {
+		minimum := script.Args[21]
+		args.MinimumUDT = &minimum
+	}
+	return args, nil
+}
+
+func pow10(exponent uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
+
+// VerifyTopUp checks a payment to an anyone-can-pay cell without signature
+// follows the lock: something must be paid, and when minimums are set, at
+// least one of them must be met.
+func (a AnyoneCanPayArgs) VerifyTopUp(capacity uint64, udtAmount *big.Int) error {
+	if udtAmount == nil {
+		udtAmount = new(big.Int)
+	}
+	if capacity == 0 && udtAmount.Sign() <= 0 {
+		return fmt.Errorf("Nothing is paid to anyone-can-pay cell!")
+	}
+	if a.MinimumCKB == nil && a.MinimumUDT == nil {
+		return nil
+	}
+	if a.MinimumCKB != nil &&
+		new(big.Int).SetUint64(capacity).Cmp(pow10(*a.MinimumCKB)) >= 0 {
+		return nil
+	}
+	if a.MinimumUDT != nil && udtAmount.Cmp(pow10(*a.MinimumUDT)) >= 0 {
+		return nil
+	}
+	return fmt.Errorf("Payment to anyone-can-pay cell is less than the minimum amount!")
+}
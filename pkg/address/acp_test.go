@@ -0,0 +1,65 @@
+package address
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestAnyoneCanPayLock(t *testing.T) {
+	minimumCKB := uint8(9)
+	minimumUDT := uint8(2)
+	args := AnyoneCanPayArgs{
+		PubkeyHash: bytes.Repeat([]byte{1}, 20),
+		MinimumCKB: &minimumCKB,
+		MinimumUDT: &minimumUDT,
+	}
+	script, err := AnyoneCanPayLock(Testnet, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script.CodeHash != TestnetAnyoneCanPayTypeHash || len(script.Args) != 22 {
+		t.Errorf("Invalid anyone-can-pay lock: %+v", script)
+	}
+	parsed, err := ParseAnyoneCanPayArgs(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.PubkeyHash, args.PubkeyHash) ||
+		*parsed.MinimumCKB != minimumCKB || *parsed.MinimumUDT != minimumUDT {
+		t.Errorf("Invalid parsed args: %+v", parsed)
+	}
+
+	_, err = AnyoneCanPayLock(Mainnet, AnyoneCanPayArgs{
+		PubkeyHash: args.PubkeyHash,
+		MinimumUDT: &minimumUDT,
+	})
+	if err == nil {
+		t.Errorf("Minimum UDT without minimum CKB is accepted!")
+	}
+}
+
+func TestVerifyTopUp(t *testing.T) {
+	minimumCKB := uint8(9)
+	minimumUDT := uint8(2)
+	args := AnyoneCanPayArgs{
+		PubkeyHash: bytes.Repeat([]byte{1}, 20),
+	}
+	if args.VerifyTopUp(0, nil) == nil {
+		t.Errorf("Empty payment is accepted!")
+	}
+	if err := args.VerifyTopUp(1, nil); err != nil {
+		t.Errorf("Payment without minimum is rejected: %v", err)
+	}
+	args.MinimumCKB = &minimumCKB
+	args.MinimumUDT = &minimumUDT
+	if args.VerifyTopUp(999999999, big.NewInt(99)) == nil {
+		t.Errorf("Payment below both minimums is accepted!")
+	}
+	if err := args.VerifyTopUp(0, big.NewInt(100)); err != nil {
+		t.Errorf("Payment meeting UDT minimum is rejected: %v", err)
+	}
+	if err := args.VerifyTopUp(1000000000, nil); err != nil {
+		t.Errorf("Payment meeting CKB minimum is rejected: %v", err)
+	}
+}
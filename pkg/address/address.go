@@ -0,0 +1,136 @@
+package address
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+const (
+	Mainnet = "ckb"
+	Testnet = "ckt"
+)
+
+const (
+	FormatFull     byte = 0x00
+	FormatShort    byte = 0x01
+	FormatFullData byte = 0x02
+	FormatFullType byte = 0x04
+)
+
+const (
+	CodeHashIndexSecp256k1Blake160 byte = 0x00
+	CodeHashIndexSecp256k1Multisig byte = 0x01
+	CodeHashIndexAnyoneCanPay      byte = 0x02
+)
+
+var (
+	Secp256k1Blake160TypeHash = rpctypes.Hash{0x9b, 0xd7, 0xe0, 0x6f, 0x3e, 0xcf, 0x4b, 0xe0, 0xf2, 0xfc, 0xd2, 0x18, 0x8b, 0x23, 0xf1, 0xb9, 0xfc, 0xc8, 0x8e, 0x5d, 0x4b, 0x65, 0xa8, 0x63, 0x7b, 0x17, 0x72, 0x3b, 0xbd, 0xa3, 0xcc, 0xe8}
+	Secp256k1MultisigTypeHash = rpctypes.Hash{0x5c, 0x50, 0x69, 0xeb, 0x08, 0x57, 0xef, 0xc6, 0x5e, 0x1b, 0xca, 0x0c, 0x07, 0xdf, 0x34, 0xc3, 0x16, 0x63, 0xb3, 0x62, 0x2f, 0xd3, 0x87, 0x6c, 0x87, 0x63, 0x20, 0xfc, 0x96, 0x34, 0xe2, 0xa8}
+
+	MainnetAnyoneCanPayTypeHash = rpctypes.Hash{0xd3, 0x69, 0x59, 0x7f, 0xf4, 0x7f, 0x29, 0xfb, 0xc0, 0xd4, 0x7d, 0x2e, 0x37, 0x75, 0x37, 0x0d, 0x12, 0x50, 0xb8, 0x51, 0x40, 0xc6, 0x70, 0xe4, 0x71, 0x8a, 0xf7, 0x12, 0x98, 0x3a, 0x23, 0x54}
+	TestnetAnyoneCanPayTypeHash = rpctypes.Hash{0x34, 0x19, 0xa1, 0xc0, 0x9e, 0xb2, 0x56, 0x7f, 0x65, 0x52, 0xee, 0x7a, 0x8e, 0xcf, 0xfd, 0x64, 0x15, 0x5c, 0xff, 0xe0, 0xf1, 0x79, 0x6e, 0x6e, 0x61, 0xec, 0x08, 0x8d, 0x74, 0x0c, 0x13, 0x56}
+)
+
+func shortCodeHash(prefix string, index byte) (rpctypes.Hash, error) {
+	switch index {
+	case CodeHashIndexSecp256k1Blake160:
+		return Secp256k1Blake160TypeHash, nil
+	case CodeHashIndexSecp256k1Multisig:
+		return Secp256k1MultisigTypeHash, nil
+	case CodeHashIndexAnyoneCanPay:
+		switch prefix {
+		case Mainnet:
+			return MainnetAnyoneCanPayTypeHash, nil
+		case Testnet:
+			return TestnetAnyoneCanPayTypeHash, nil
+		}
+	}
+	return rpctypes.Hash{}, fmt.Errorf("Invalid code hash index %d for prefix %s!", index, prefix)
+}
+
+// Parse decodes a CKB address in any of the short, full data, full type or
+// full formats, returning the network prefix together with the lock script.
+func Parse(address string) (string, rpctypes.Script, error) {
+	var script rpctypes.Script
+	prefix, data, e, err := decode(address)
+	if err != nil {
+		return "", script, err
+	}
+	payload, err := convertBits(data, 5, 8, false)
+	if err != nil {
+		return "", script, err
+	}
+	if len(payload) == 0 {
+		return "", script, fmt.Errorf("Empty address payload!")
+	}
+	format := payload[0]
+	body := payload[1:]
+	if (format == FormatFull) != (e == bech32m) {
+		return "", script, fmt.Errorf("Invalid checksum encoding for address format %d!", format)
+	}
+	switch format {
+	case FormatShort:
+		if len(body) < 1 {
+			return "", script, fmt.Errorf("Invalid short address payload length: %d", len(payload))
+		}
+		script.CodeHash, err = shortCodeHash(prefix, body[0])
+		if err != nil {
+			return "", script, err
+		}
+		// Anyone-can-pay args might carry minimums of CKB and UDT amount.
+		argsLength := len(body) - 1
+		if argsLength != 20 && (body[0] != CodeHashIndexAnyoneCanPay || argsLength > 22) {
+			return "", script, fmt.Errorf("Invalid short address args length: %d", argsLength)
+		}
+		script.HashType = rpctypes.Type
+		script.Args = rpctypes.Bytes(body[1:])
+	case FormatFullData, FormatFullType:
+		if len(body) < 32 {
+			return "", script, fmt.Errorf("Invalid full address payload length: %d", len(payload))
+		}
+		copy(script.CodeHash[:], body[0:32])
+		if format == FormatFullType {
+			script.HashType = rpctypes.Type
+		} else {
+			script.HashType = rpctypes.Data
+		}
+		script.Args = rpctypes.Bytes(body[32:])
+	case FormatFull:
+		if len(body) < 33 {
+			return "", script, fmt.Errorf("Invalid full address payload length: %d", len(payload))
+		}
+		copy(script.CodeHash[:], body[0:32])
+		hashType := rpctypes.ScriptHashType(body[32])
+		if hashType > rpctypes.Data1 {
+			return "", script, fmt.Errorf("Unsupported script hash type: %d", hashType)
+		}
+		script.HashType = hashType
+		script.Args = rpctypes.Bytes(body[33:])
+	default:
+		return "", script, fmt.Errorf("Invalid address format: %d", format)
+	}
+	return prefix, script, nil
+}
+
+// Generate encodes script into a full format(bech32m) address using the
+// given network prefix.
+func Generate(prefix string, script rpctypes.Script) (string, error) {
+	if len(prefix) == 0 {
+		return "", fmt.Errorf("Address prefix is missing!")
+	}
+	if script.HashType > rpctypes.Data1 {
+		return "", fmt.Errorf("Invalid script hash type!")
+	}
+	var buffer bytes.Buffer
+	buffer.WriteByte(FormatFull)
+	buffer.Write(script.CodeHash[:])
+	buffer.WriteByte(byte(script.HashType))
+	buffer.Write(script.Args)
+	data, err := convertBits(buffer.Bytes(), 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return encode(prefix, data, bech32m), nil
+}
@@ -0,0 +1,125 @@
+package address
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func assertScript(t *testing.T, script rpctypes.Script, codeHash string, hashType rpctypes.ScriptHashType, args string) {
+	if !bytes.Equal(script.CodeHash[:], decodeHex(t, codeHash)) {
+		t.Errorf("Invalid code hash: %x, expected: %s", script.CodeHash[:], codeHash)
+	}
+	if script.HashType != hashType {
+		t.Errorf("Invalid hash type: %d, expected: %d", script.HashType, hashType)
+	}
+	if !bytes.Equal(script.Args, decodeHex(t, args)) {
+		t.Errorf("Invalid args: %x, expected: %s", []byte(script.Args), args)
+	}
+}
+
+func TestParseShortAddress(t *testing.T) {
+	prefix, script, err := Parse("ckb1qyqt8xaupvm8837nv3gtc9x0ekkj64vud3jqfwyw5v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != Mainnet {
+		t.Errorf("Invalid prefix: %s", prefix)
+	}
+	assertScript(t, script,
+		"9bd7e06f3ecf4be0f2fcd2188b23f1b9fcc88e5d4b65a8637b17723bbda3cce8",
+		rpctypes.Type, "b39bbc0b3673c7d36450bc14cfcdad2d559c6c64")
+}
+
+func TestParseFullAddress(t *testing.T) {
+	prefix, script, err := Parse("ckb1qzda0cr08m85hc8jlnfp3zer7xulejywt49kt2rr0vthywaa50xwsqdnnw7qkdnnclfkg59uzn8umtfd2kwxceqxwquc4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != Mainnet {
+		t.Errorf("Invalid prefix: %s", prefix)
+	}
+	assertScript(t, script,
+		"9bd7e06f3ecf4be0f2fcd2188b23f1b9fcc88e5d4b65a8637b17723bbda3cce8",
+		rpctypes.Type, "b39bbc0b3673c7d36450bc14cfcdad2d559c6c64")
+}
+
+func TestGenerateAddress(t *testing.T) {
+	script := rpctypes.Script{
+		HashType: rpctypes.Type,
+		Args:     decodeHex(t, "b39bbc0b3673c7d36450bc14cfcdad2d559c6c64"),
+	}
+	copy(script.CodeHash[:], Secp256k1Blake160TypeHash[:])
+	address, err := Generate(Mainnet, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "ckb1qzda0cr08m85hc8jlnfp3zer7xulejywt49kt2rr0vthywaa50xwsqdnnw7qkdnnclfkg59uzn8umtfd2kwxceqxwquc4"
+	if address != expected {
+		t.Errorf("Invalid address: %s, expected: %s", address, expected)
+	}
+}
+
+func TestParseInvalidChecksum(t *testing.T) {
+	_, _, err := Parse("ckb1qyqt8xaupvm8837nv3gtc9x0ekkj64vud3jqfwyw5w")
+	if err == nil {
+		t.Errorf("Address with invalid checksum is accepted!")
+	}
+}
+
+func shortAddress(t *testing.T, index byte, args []byte) string {
+	data, err := convertBits(append([]byte{FormatShort, index}, args...), 8, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encode(Mainnet, data, bech32)
+}
+
+func TestParseShortAddressArgsLength(t *testing.T) {
+	for _, c := range []struct {
+		index  byte
+		length int
+		valid  bool
+	}{
+		{CodeHashIndexSecp256k1Blake160, 20, true},
+		{CodeHashIndexSecp256k1Blake160, 19, false},
+		{CodeHashIndexSecp256k1Blake160, 21, false},
+		{CodeHashIndexSecp256k1Multisig, 22, false},
+		{CodeHashIndexAnyoneCanPay, 22, true},
+		{CodeHashIndexAnyoneCanPay, 23, false},
+	} {
+		_, _, err := Parse(shortAddress(t, c.index, make([]byte, c.length)))
+		if (err == nil) != c.valid {
+			t.Errorf("Unexpected result for code hash index %d with %d bytes args: %v", c.index, c.length, err)
+		}
+	}
+}
+
+func TestData1Address(t *testing.T) {
+	script := rpctypes.Script{
+		HashType: rpctypes.Data1,
+		Args:     decodeHex(t, "b39bbc0b3673c7d36450bc14cfcdad2d559c6c64"),
+	}
+	copy(script.CodeHash[:], Secp256k1Blake160TypeHash[:])
+	address, err := Generate(Mainnet, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, parsed, err := Parse(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertScript(t, parsed,
+		"9bd7e06f3ecf4be0f2fcd2188b23f1b9fcc88e5d4b65a8637b17723bbda3cce8",
+		rpctypes.Data1, "b39bbc0b3673c7d36450bc14cfcdad2d559c6c64")
+}
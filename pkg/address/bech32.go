@@ -0,0 +1,134 @@
+package address
+
+import (
+	"fmt"
+	"strings"
+)
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+type encoding int
+
+const (
+	bech32 encoding = iota + 1
+	bech32m
+)
+
+func (e encoding) constant() uint32 {
+	if e == bech32m {
+		return 0x2bc830a3
+	}
+	return 1
+}
+
+func polymod(values []byte) uint32 {
+	generators := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generators[i]
+			}
+		}
+	}
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	result := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]>>5)
+	}
+	result = append(result, 0)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]&31)
+	}
+	return result
+}
+
+func createChecksum(hrp string, data []byte, e encoding) []byte {
+	values := append(hrpExpand(hrp), data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	mod := polymod(values) ^ e.constant()
+	result := make([]byte, 6)
+	for i := 0; i < 6; i++ {
+		result[i] = byte((mod >> uint(5*(5-i))) & 31)
+	}
+	return result
+}
+
+func encode(hrp string, data []byte, e encoding) string {
+	combined := make([]byte, 0, len(data)+6)
+	combined = append(combined, data...)
+	combined = append(combined, createChecksum(hrp, data, e)...)
+	var builder strings.Builder
+	builder.WriteString(hrp)
+	builder.WriteByte('1')
+	for _, d := range combined {
+		builder.WriteByte(charset[d])
+	}
+	return builder.String()
+}
+
+func decode(s string) (string, []byte, encoding, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, fmt.Errorf("Mixed case address!")
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, 0, fmt.Errorf("Invalid separator position!")
+	}
+	hrp := s[:pos]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, 0, fmt.Errorf("Invalid character in prefix!")
+		}
+	}
+	data := make([]byte, len(s)-pos-1)
+	for i := range data {
+		d := strings.IndexByte(charset, s[pos+1+i])
+		if d == -1 {
+			return "", nil, 0, fmt.Errorf("Invalid character in address: %c", s[pos+1+i])
+		}
+		data[i] = byte(d)
+	}
+	var e encoding
+	switch polymod(append(hrpExpand(hrp), data...)) {
+	case bech32.constant():
+		e = bech32
+	case bech32m.constant():
+		e = bech32m
+	default:
+		return "", nil, 0, fmt.Errorf("Invalid address checksum!")
+	}
+	return hrp, data[:len(data)-6], e, nil
+}
+
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	acc := uint32(0)
+	bits := uint(0)
+	maxv := uint32(1)<<toBits - 1
+	result := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, value := range data {
+		if uint32(value)>>fromBits != 0 {
+			return nil, fmt.Errorf("Invalid data range!")
+		}
+		acc = acc<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte((acc>>bits)&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			result = append(result, byte((acc<<(toBits-bits))&maxv))
+		}
+	} else if bits >= fromBits || (acc<<(toBits-bits))&maxv != 0 {
+		return nil, fmt.Errorf("Invalid padding!")
+	}
+	return result, nil
+}
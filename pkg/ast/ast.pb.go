@@ -100,6 +100,35 @@ const (
 	Value_MULTIPLY          Value_Type = 87
 	Value_DIVIDE            Value_Type = 88
 	Value_MOD               Value_Type = 89
+	// Address operations
+	Value_DECODE_ADDRESS Value_Type = 90
+	Value_ENCODE_ADDRESS Value_Type = 91
+	// Nervos DAO operations
+	Value_DECODE_DAO           Value_Type = 92
+	Value_DECODE_EPOCH         Value_Type = 93
+	Value_DAO_MAXIMUM_WITHDRAW Value_Type = 94
+	// Since operations
+	Value_ENCODE_SINCE Value_Type = 95
+	Value_DECODE_SINCE Value_Type = 96
+	// Capacity operations
+	Value_OCCUPIED_CAPACITY Value_Type = 97
+	// Fee operations
+	Value_FEE        Value_Type = 98
+	Value_DEDUCT_FEE Value_Type = 99
+	// Signing operations
+	Value_SIGNING_ENTRIES Value_Type = 100
+	// [SERIALIZE_TO_JSON(tx), SIGNING_ENTRIES(tx)], so a transaction can be
+	// returned together with its signing entries.
+	Value_SIGNING_REQUEST Value_Type = 107
+	// Multisig operations
+	Value_MULTISIG_LOCK_ARGS Value_Type = 101
+	Value_MULTISIG_WITNESS   Value_Type = 102
+	// Anyone-can-pay operations
+	Value_ACP_LOCK    Value_Type = 103
+	Value_IS_ACP_LOCK Value_Type = 104
+	Value_ACP_TOP_UP  Value_Type = 105
+	// Type ID operations
+	Value_TYPE_ID Value_Type = 106
 	// Special operations
 	Value_COND           Value_Type = 120
 	Value_TAIL_RECURSION Value_Type = 121
@@ -167,6 +196,24 @@ var Value_Type_name = map[int32]string{
 	87:  "MULTIPLY",
 	88:  "DIVIDE",
 	89:  "MOD",
+	90:  "DECODE_ADDRESS",
+	91:  "ENCODE_ADDRESS",
+	92:  "DECODE_DAO",
+	93:  "DECODE_EPOCH",
+	94:  "DAO_MAXIMUM_WITHDRAW",
+	95:  "ENCODE_SINCE",
+	96:  "DECODE_SINCE",
+	97:  "OCCUPIED_CAPACITY",
+	98:  "FEE",
+	99:  "DEDUCT_FEE",
+	100: "SIGNING_ENTRIES",
+	107: "SIGNING_REQUEST",
+	101: "MULTISIG_LOCK_ARGS",
+	102: "MULTISIG_WITNESS",
+	103: "ACP_LOCK",
+	104: "IS_ACP_LOCK",
+	105: "ACP_TOP_UP",
+	106: "TYPE_ID",
 	120: "COND",
 	121: "TAIL_RECURSION",
 }
@@ -233,6 +280,24 @@ var Value_Type_value = map[string]int32{
 	"MULTIPLY":              87,
 	"DIVIDE":                88,
 	"MOD":                   89,
+	"DECODE_ADDRESS":        90,
+	"ENCODE_ADDRESS":        91,
+	"DECODE_DAO":            92,
+	"DECODE_EPOCH":          93,
+	"DAO_MAXIMUM_WITHDRAW":  94,
+	"ENCODE_SINCE":          95,
+	"DECODE_SINCE":          96,
+	"OCCUPIED_CAPACITY":     97,
+	"FEE":                   98,
+	"DEDUCT_FEE":            99,
+	"SIGNING_ENTRIES":       100,
+	"SIGNING_REQUEST":       107,
+	"MULTISIG_LOCK_ARGS":    101,
+	"MULTISIG_WITNESS":      102,
+	"ACP_LOCK":              103,
+	"IS_ACP_LOCK":           104,
+	"ACP_TOP_UP":            105,
+	"TYPE_ID":               106,
 	"COND":                  120,
 	"TAIL_RECURSION":        121,
 }
@@ -247,6 +312,9 @@ func (Value_Type) EnumDescriptor() ([]byte, []int) {
 
 type Value struct {
 	T Value_Type `protobuf:"varint,1,opt,name=t,proto3,enum=ast.Value_Type" json:"t,omitempty"`
+	// For TRANSACTION values, u contains bit flags of extra checks to run on
+	// the assembled transaction.
+	//
 	// Types that are valid to be assigned to Primitive:
 	//	*Value_B
 	//	*Value_U
@@ -508,58 +576,67 @@ func init() {
 func init() { proto.RegisterFile("ast.proto", fileDescriptor_37b5b141da493253) }
 
 var fileDescriptor_37b5b141da493253 = []byte{
-	// 836 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x54, 0xdd, 0x72, 0xdb, 0x36,
-	0x13, 0x35, 0x2d, 0xd9, 0x96, 0x20, 0xc7, 0x5e, 0x23, 0x9f, 0xf3, 0x29, 0x6d, 0xd3, 0x6a, 0xd4,
-	0x49, 0x47, 0x57, 0x76, 0xeb, 0xa4, 0xe9, 0x7f, 0x1a, 0x08, 0x44, 0x24, 0x24, 0x14, 0x40, 0x03,
-	0xa0, 0x13, 0xe5, 0x86, 0x43, 0xbb, 0xac, 0xa3, 0x56, 0xb2, 0x3c, 0x12, 0xd5, 0x3a, 0x8f, 0xd2,
-	0x67, 0xe8, 0x4b, 0x76, 0x16, 0x14, 0xe3, 0x69, 0x3b, 0xbd, 0xe3, 0x9e, 0x3d, 0x7b, 0x76, 0x01,
-	0x1c, 0x2e, 0x69, 0x66, 0xcb, 0xe2, 0xe8, 0x7a, 0x31, 0x2f, 0xe6, 0xb4, 0x96, 0x2d, 0x8b, 0xee,
-	0x9f, 0x0d, 0xb2, 0x75, 0x96, 0x4d, 0x57, 0x39, 0x7d, 0x40, 0x82, 0xa2, 0x1d, 0x74, 0x82, 0xde,
-	0xde, 0xc9, 0xfe, 0x11, 0xb2, 0x3c, 0x7c, 0xe4, 0xde, 0x5d, 0xe7, 0x26, 0x28, 0xe8, 0x1e, 0x09,
-	0xce, 0xdb, 0x9b, 0x9d, 0xa0, 0xd7, 0x18, 0x6e, 0x98, 0xe0, 0x1c, 0xe3, 0x55, 0xbb, 0xd6, 0x09,
-	0x7a, 0x75, 0x8c, 0x57, 0x94, 0x92, 0xda, 0x22, 0xfb, 0xbd, 0x5d, 0xef, 0x04, 0xbd, 0xdd, 0xe1,
-	0x86, 0xc1, 0x80, 0x7e, 0x46, 0x1a, 0x17, 0x6f, 0x27, 0xd3, 0x9f, 0x16, 0xf9, 0x55, 0xbb, 0xd1,
-	0xa9, 0xf5, 0x5a, 0x27, 0xe4, 0x56, 0xd9, 0xbc, 0xcf, 0x75, 0xff, 0xd8, 0x21, 0x75, 0xec, 0x43,
-	0x77, 0x48, 0x4d, 0xc9, 0x08, 0x36, 0x28, 0x21, 0xdb, 0x89, 0x54, 0xee, 0xc9, 0x63, 0x08, 0x68,
-	0x83, 0xd4, 0xfb, 0x5a, 0x47, 0xb0, 0x49, 0x9b, 0x64, 0xab, 0x3f, 0x76, 0xc2, 0x42, 0x0d, 0x3f,
-	0x85, 0x31, 0xda, 0x40, 0x1d, 0x8b, 0x98, 0x19, 0x00, 0x20, 0x16, 0x33, 0xc3, 0x46, 0x70, 0x40,
-	0xef, 0x90, 0xa6, 0x4e, 0x5c, 0x1a, 0x6b, 0xa9, 0x1c, 0x50, 0xba, 0x47, 0x08, 0x17, 0x51, 0x94,
-	0x4a, 0x15, 0x27, 0x0e, 0xee, 0xd2, 0x5d, 0xd2, 0xf0, 0x71, 0x28, 0x62, 0xf8, 0x1f, 0x36, 0xb3,
-	0xdc, 0xc8, 0xd8, 0xc1, 0x21, 0x36, 0xc3, 0x0c, 0xdc, 0xa3, 0xfb, 0xa4, 0xe5, 0x0c, 0x53, 0x96,
-	0x71, 0x27, 0xb5, 0x82, 0xff, 0x23, 0x6d, 0x28, 0x58, 0x28, 0x0c, 0xb4, 0xb1, 0x15, 0x8b, 0xe3,
-	0x68, 0x0c, 0xf7, 0x11, 0x36, 0x22, 0x4c, 0xb8, 0x80, 0x0f, 0xb0, 0x3a, 0x92, 0xd6, 0xc1, 0x87,
-	0x58, 0x7d, 0x9a, 0x08, 0x33, 0x4e, 0x51, 0xcd, 0xc2, 0x47, 0x38, 0xe5, 0x88, 0xc5, 0xf0, 0x00,
-	0xf9, 0xcf, 0x65, 0xe4, 0x84, 0x81, 0x8f, 0x29, 0x90, 0xdd, 0x81, 0x70, 0x29, 0x67, 0x31, 0xe3,
-	0xd2, 0x8d, 0xe1, 0x73, 0x9c, 0x0c, 0x91, 0x90, 0x39, 0x06, 0x5f, 0x54, 0x51, 0xa4, 0xf9, 0x4b,
-	0x38, 0xa9, 0x22, 0x37, 0x8e, 0x05, 0x3c, 0xa2, 0x07, 0xe4, 0x4e, 0xc5, 0x4c, 0x87, 0xcc, 0x0e,
-	0xe1, 0x71, 0x05, 0xdd, 0x9e, 0xfc, 0xcb, 0x0a, 0xe2, 0x3a, 0x14, 0x25, 0xeb, 0x49, 0x05, 0x61,
-	0x54, 0x6a, 0x7d, 0x55, 0x29, 0x33, 0x33, 0xb0, 0xf0, 0xf5, 0xfb, 0x9a, 0xf5, 0x0d, 0x59, 0xf8,
-	0x86, 0xde, 0x25, 0xfb, 0xbe, 0xc6, 0x9f, 0xbf, 0x04, 0xbf, 0xc5, 0x5b, 0x45, 0xd0, 0x5f, 0xaa,
-	0x85, 0xef, 0xf0, 0xcc, 0xeb, 0xf6, 0x1e, 0xf8, 0xbe, 0x12, 0x7a, 0x25, 0x9d, 0x12, 0xd6, 0x0a,
-	0x0b, 0x3f, 0xd0, 0x7b, 0x84, 0x96, 0xf3, 0x8c, 0x62, 0xc6, 0x5d, 0xea, 0x98, 0x19, 0x08, 0x07,
-	0x4f, 0x2b, 0xaa, 0x93, 0x23, 0x61, 0x1d, 0x1b, 0xc5, 0xf0, 0x63, 0x25, 0xaf, 0x92, 0x51, 0x5f,
-	0x18, 0x78, 0x86, 0x6f, 0x8a, 0xb1, 0x88, 0x35, 0x1f, 0x02, 0xab, 0x46, 0x8a, 0x99, 0x11, 0xaa,
-	0x3c, 0x0d, 0xf4, 0xe9, 0x7d, 0x72, 0xe8, 0x65, 0x6e, 0x1f, 0xce, 0xa6, 0x46, 0x6b, 0x07, 0xbc,
-	0xea, 0x1c, 0x1b, 0x1d, 0x6b, 0xcb, 0x22, 0x5b, 0x96, 0x84, 0x95, 0x4e, 0xa2, 0x78, 0x24, 0xd6,
-	0xa0, 0xa0, 0x2d, 0xb2, 0x53, 0x5e, 0xae, 0x86, 0xe7, 0x55, 0x63, 0xa5, 0x15, 0x17, 0x30, 0xa8,
-	0xe6, 0x5a, 0x7b, 0x61, 0x88, 0x8f, 0xee, 0xab, 0x24, 0x3d, 0x24, 0x07, 0x56, 0x18, 0xc9, 0x22,
-	0xf9, 0x46, 0xa4, 0x4e, 0xa7, 0x5c, 0x1b, 0x01, 0x2f, 0xfe, 0x05, 0xbf, 0xb0, 0x5a, 0xc1, 0x4b,
-	0x6f, 0x76, 0xed, 0x20, 0xf2, 0x06, 0x56, 0x21, 0x8c, 0xe8, 0x36, 0xd9, 0xd4, 0x06, 0x94, 0x37,
-	0xf7, 0x69, 0xc2, 0x22, 0x88, 0xbd, 0xa3, 0x84, 0xb5, 0x70, 0x8a, 0xac, 0x48, 0x28, 0x30, 0x98,
-	0xb5, 0x91, 0xe4, 0x02, 0x2c, 0x7e, 0x4a, 0x15, 0x8a, 0xd7, 0xe0, 0xbc, 0x48, 0x18, 0x42, 0x82,
-	0x6f, 0x69, 0x93, 0xbe, 0x33, 0x8c, 0x3b, 0x38, 0xc3, 0x68, 0x94, 0x44, 0x4e, 0xa2, 0x57, 0x5f,
-	0xa1, 0xf7, 0x42, 0x79, 0x26, 0x43, 0x01, 0xaf, 0xbd, 0x21, 0x75, 0x08, 0x63, 0x6f, 0x79, 0xad,
-	0x42, 0xb8, 0xa1, 0x94, 0xec, 0x39, 0x26, 0xa3, 0xd4, 0x08, 0x9e, 0x18, 0x8b, 0xae, 0x7f, 0xd7,
-	0x6f, 0x91, 0xe6, 0xf5, 0x62, 0x32, 0x9b, 0x14, 0x93, 0xdf, 0xf2, 0xee, 0x53, 0x52, 0xe7, 0xd9,
-	0x74, 0x4a, 0x29, 0xa9, 0x5f, 0x65, 0xb3, 0xdc, 0xaf, 0x8b, 0xa6, 0xf1, 0xdf, 0xb4, 0x4b, 0xb6,
-	0x17, 0xf9, 0x72, 0x35, 0x2d, 0xfc, 0x56, 0xf8, 0xfb, 0xaf, 0xbe, 0xce, 0x74, 0x9f, 0x91, 0x6d,
-	0x5b, 0x2c, 0xf2, 0x6c, 0xf6, 0x5f, 0x0a, 0x3f, 0x4f, 0xa6, 0x45, 0xbe, 0xf0, 0x7b, 0xe6, 0x1f,
-	0x0a, 0x65, 0xa6, 0xab, 0x48, 0xdd, 0xcc, 0xe7, 0x05, 0xfd, 0x84, 0x6c, 0x5d, 0x64, 0xd3, 0xe9,
-	0xb2, 0x1d, 0xf8, 0xbd, 0xd2, 0xf4, 0x54, 0x9c, 0xcd, 0x94, 0x38, 0x7d, 0x48, 0x76, 0x96, 0xbe,
-	0xd5, 0xb2, 0xbd, 0xe9, 0x29, 0x2d, 0x4f, 0x29, 0xdb, 0x9b, 0x2a, 0xd7, 0x7f, 0xf8, 0xe6, 0xd3,
-	0xcb, 0x49, 0xf1, 0x76, 0x75, 0x7e, 0x74, 0x31, 0x9f, 0x1d, 0xdf, 0xdc, 0xac, 0xf2, 0x5f, 0x26,
-	0xf9, 0x71, 0x76, 0x35, 0x99, 0x65, 0x97, 0xab, 0xe5, 0xf1, 0xf5, 0xaf, 0x97, 0xc7, 0xd9, 0xb2,
-	0x38, 0xdf, 0xf6, 0x2b, 0xf3, 0xd1, 0x5f, 0x01, 0x00, 0x00, 0xff, 0xff, 0xde, 0x1b, 0x30, 0xe7,
-	0x3f, 0x05, 0x00, 0x00,
+	// 989 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x55, 0x6d, 0x53, 0x1b, 0x37,
+	0x10, 0x8e, 0xb1, 0x03, 0x58, 0x10, 0x58, 0x14, 0x48, 0x9d, 0xb6, 0x69, 0x19, 0x77, 0xd2, 0xe1,
+	0x13, 0xb4, 0x24, 0x4d, 0xdf, 0xd3, 0xc8, 0x92, 0xb0, 0x95, 0x9c, 0xa5, 0x43, 0xd2, 0x01, 0xa6,
+	0x2f, 0xd7, 0x83, 0x38, 0xe0, 0xc4, 0xbc, 0x8c, 0x7d, 0x6e, 0xc9, 0x0f, 0xee, 0x6f, 0xe8, 0xd7,
+	0xce, 0xea, 0x7c, 0x90, 0x4c, 0xa6, 0xdf, 0xb4, 0x8f, 0x9e, 0x7d, 0xd1, 0xee, 0x73, 0x7b, 0xa4,
+	0x9e, 0x8d, 0xf3, 0xcd, 0xcb, 0xd1, 0x45, 0x7e, 0x41, 0xab, 0xd9, 0x38, 0x6f, 0xfe, 0x4b, 0xc8,
+	0xed, 0xbd, 0x6c, 0x38, 0xe9, 0xd3, 0x07, 0xa4, 0x92, 0x37, 0x2a, 0xeb, 0x95, 0x8d, 0xa5, 0xed,
+	0xe5, 0x4d, 0x64, 0x05, 0x78, 0xd3, 0xbf, 0xbd, 0xec, 0xdb, 0x4a, 0x4e, 0x97, 0x48, 0xe5, 0xa8,
+	0x31, 0xb3, 0x5e, 0xd9, 0x98, 0xef, 0xdc, 0xb2, 0x95, 0x23, 0xb4, 0x27, 0x8d, 0xea, 0x7a, 0x65,
+	0xa3, 0x86, 0xf6, 0x84, 0x52, 0x52, 0x1d, 0x65, 0x7f, 0x37, 0x6a, 0xeb, 0x95, 0x8d, 0xc5, 0xce,
+	0x2d, 0x8b, 0x06, 0xfd, 0x92, 0xcc, 0x1f, 0x9f, 0x0e, 0x86, 0x2f, 0x47, 0xfd, 0xf3, 0xc6, 0xfc,
+	0x7a, 0x75, 0x63, 0x61, 0x9b, 0xdc, 0x44, 0xb6, 0xd7, 0x77, 0xcd, 0x7f, 0xea, 0xa4, 0x86, 0x79,
+	0xe8, 0x1c, 0xa9, 0x6a, 0x15, 0xc1, 0x2d, 0x4a, 0xc8, 0x6c, 0xa2, 0xb4, 0x7f, 0xf2, 0x18, 0x2a,
+	0x74, 0x9e, 0xd4, 0x5a, 0xc6, 0x44, 0x30, 0x43, 0xeb, 0xe4, 0x76, 0xab, 0xe7, 0xa5, 0x83, 0x2a,
+	0x1e, 0xa5, 0xb5, 0xc6, 0x42, 0x0d, 0x9d, 0x98, 0x6d, 0x03, 0x20, 0x16, 0x33, 0xcb, 0xba, 0xb0,
+	0x42, 0xef, 0x90, 0xba, 0x49, 0x7c, 0x1a, 0x1b, 0xa5, 0x3d, 0x50, 0xba, 0x44, 0x08, 0x97, 0x51,
+	0x94, 0x2a, 0x1d, 0x27, 0x1e, 0xee, 0xd2, 0x45, 0x32, 0x1f, 0x6c, 0x21, 0x63, 0x58, 0xc5, 0x64,
+	0x8e, 0x5b, 0x15, 0x7b, 0x58, 0xc3, 0x64, 0x78, 0x03, 0xf7, 0xe8, 0x32, 0x59, 0xf0, 0x96, 0x69,
+	0xc7, 0xb8, 0x57, 0x46, 0xc3, 0x47, 0x48, 0xeb, 0x48, 0x26, 0xa4, 0x85, 0x06, 0xa6, 0x62, 0x71,
+	0x1c, 0xf5, 0xe0, 0x3e, 0xc2, 0x56, 0x8a, 0x84, 0x4b, 0xf8, 0x18, 0xbd, 0x23, 0xe5, 0x3c, 0x7c,
+	0x82, 0xde, 0xbb, 0x89, 0xb4, 0xbd, 0x14, 0xa3, 0x39, 0xf8, 0x14, 0xab, 0xec, 0xb2, 0x18, 0x1e,
+	0x20, 0x7f, 0x47, 0x45, 0x5e, 0x5a, 0xf8, 0x8c, 0x02, 0x59, 0x6c, 0x4b, 0x9f, 0x72, 0x16, 0x33,
+	0xae, 0x7c, 0x0f, 0xbe, 0xc2, 0xca, 0x10, 0x11, 0xcc, 0x33, 0xf8, 0xba, 0xb4, 0x22, 0xc3, 0x5f,
+	0xc0, 0x76, 0x69, 0xf9, 0x5e, 0x2c, 0xe1, 0x11, 0x5d, 0x21, 0x77, 0x4a, 0x66, 0xda, 0x61, 0xae,
+	0x03, 0x8f, 0x4b, 0xe8, 0xe6, 0xe5, 0xdf, 0x94, 0x10, 0x37, 0x42, 0x16, 0xac, 0x27, 0x25, 0x84,
+	0x56, 0x11, 0xeb, 0xdb, 0x32, 0x32, 0xb3, 0x6d, 0x07, 0xdf, 0x5d, 0xfb, 0x4c, 0x3b, 0xe4, 0xe0,
+	0x7b, 0x7a, 0x97, 0x2c, 0x07, 0x9f, 0xf0, 0xfe, 0x02, 0xfc, 0x01, 0xbb, 0x8a, 0x60, 0x68, 0xaa,
+	0x83, 0x1f, 0xf1, 0xcd, 0xd3, 0xf4, 0x01, 0xf8, 0xa9, 0x0c, 0xb4, 0xaf, 0xbc, 0x96, 0xce, 0x49,
+	0x07, 0x3f, 0xd3, 0x7b, 0x84, 0x16, 0xf5, 0x74, 0x63, 0xc6, 0x7d, 0xea, 0x99, 0x6d, 0x4b, 0x0f,
+	0x4f, 0x4b, 0xaa, 0x57, 0x5d, 0xe9, 0x3c, 0xeb, 0xc6, 0xf0, 0x4b, 0x19, 0x5e, 0x27, 0xdd, 0x96,
+	0xb4, 0xf0, 0x0c, 0x67, 0x8a, 0xb6, 0x8c, 0x0d, 0xef, 0x00, 0x2b, 0x4b, 0x8a, 0x99, 0x95, 0xba,
+	0x78, 0x0d, 0xb4, 0xe8, 0x7d, 0xb2, 0x16, 0xc2, 0xdc, 0x0c, 0xce, 0xa5, 0xd6, 0x18, 0x0f, 0xbc,
+	0xcc, 0x1c, 0x5b, 0x13, 0x1b, 0xc7, 0x22, 0x57, 0xb8, 0x88, 0x32, 0x4e, 0xa2, 0x79, 0x24, 0xa7,
+	0xa0, 0xa4, 0x0b, 0x64, 0xae, 0x68, 0xae, 0x81, 0x9d, 0x32, 0xb1, 0x36, 0x9a, 0x4b, 0x68, 0x97,
+	0x75, 0x4d, 0xb5, 0xd0, 0xc1, 0xa1, 0x07, 0x2f, 0x45, 0xd7, 0xc8, 0x8a, 0x93, 0x56, 0xb1, 0x48,
+	0x1d, 0xca, 0xd4, 0x9b, 0x94, 0x1b, 0x2b, 0xe1, 0xf9, 0x07, 0xf0, 0x73, 0x67, 0x34, 0xbc, 0x08,
+	0x62, 0x37, 0x1e, 0x22, 0x3c, 0x30, 0x2d, 0xa0, 0x4b, 0x67, 0xc9, 0x8c, 0xb1, 0xa0, 0x83, 0xb8,
+	0x77, 0x13, 0x16, 0x41, 0x1c, 0x14, 0x25, 0x9d, 0x83, 0x5d, 0x64, 0x45, 0x52, 0x83, 0xc5, 0x5b,
+	0x17, 0x29, 0x2e, 0xc1, 0xe1, 0x51, 0x69, 0x21, 0x0f, 0xc0, 0x87, 0x20, 0x42, 0x40, 0x82, 0xb3,
+	0x74, 0x49, 0xcb, 0x5b, 0xc6, 0x3d, 0xec, 0xa1, 0xd5, 0x4d, 0x22, 0xaf, 0x50, 0xab, 0xfb, 0xa8,
+	0x3d, 0xa1, 0xf6, 0x94, 0x90, 0x70, 0x10, 0x04, 0x69, 0x04, 0xf4, 0x28, 0x25, 0x4b, 0x42, 0x06,
+	0x81, 0x30, 0x21, 0x2c, 0x26, 0x3b, 0x44, 0x4c, 0xea, 0xf7, 0xb0, 0x5f, 0xf1, 0xdd, 0x53, 0x1e,
+	0xb6, 0xe5, 0x37, 0x14, 0xef, 0xd4, 0x2e, 0x46, 0xf2, 0x3b, 0x6d, 0x90, 0x55, 0xc1, 0x4c, 0xda,
+	0x65, 0x07, 0xaa, 0x9b, 0x74, 0x71, 0xee, 0x1d, 0x61, 0xd9, 0x3e, 0xfc, 0x81, 0xdc, 0x69, 0x3c,
+	0xa7, 0xb0, 0x8b, 0xe9, 0x3b, 0xde, 0x05, 0xf2, 0x27, 0xb6, 0xc9, 0x70, 0x9e, 0xc4, 0x4a, 0x8a,
+	0x9b, 0x2f, 0x22, 0xc3, 0x3a, 0x77, 0xa4, 0x84, 0xa3, 0x22, 0xbf, 0x48, 0xb8, 0x4f, 0xd1, 0x3e,
+	0xc6, 0xc1, 0x39, 0xd5, 0xd6, 0x4a, 0xb7, 0x53, 0xa9, 0xbd, 0x55, 0xd2, 0xc1, 0xcb, 0x77, 0x41,
+	0x2b, 0x77, 0x13, 0xe9, 0x3c, 0xbc, 0xc1, 0xd1, 0x87, 0x26, 0x38, 0xd5, 0x0e, 0xdf, 0x52, 0x21,
+	0xf4, 0x3e, 0x5d, 0x25, 0x70, 0x8d, 0x4f, 0x45, 0x0a, 0xaf, 0xb0, 0x65, 0x8c, 0xc7, 0x81, 0x08,
+	0x27, 0x28, 0x6a, 0xe5, 0xd2, 0x6b, 0xe0, 0x14, 0xcb, 0x40, 0xcb, 0x9b, 0x38, 0x4d, 0x62, 0x18,
+	0xa0, 0x54, 0xf0, 0x2b, 0x4a, 0x95, 0x80, 0xd7, 0x61, 0x7d, 0x18, 0x2d, 0xe0, 0x0a, 0x3b, 0xe8,
+	0x99, 0x8a, 0x52, 0x2b, 0x79, 0x62, 0x1d, 0x6e, 0x90, 0xb7, 0xad, 0x05, 0x52, 0xbf, 0x1c, 0x0d,
+	0xce, 0x06, 0xf9, 0xe0, 0xaf, 0x7e, 0xf3, 0x29, 0xa9, 0xf1, 0x6c, 0x38, 0xa4, 0x94, 0xd4, 0xce,
+	0xb3, 0xb3, 0x7e, 0x58, 0xbd, 0x75, 0x1b, 0xce, 0xb4, 0x49, 0x66, 0x47, 0xfd, 0xf1, 0x64, 0x98,
+	0x87, 0x0d, 0xfb, 0xfe, 0xda, 0x9c, 0xde, 0x34, 0x9f, 0x91, 0x59, 0x97, 0x8f, 0xfa, 0xd9, 0xd9,
+	0xff, 0x45, 0x78, 0x35, 0x18, 0xe6, 0xfd, 0x51, 0x63, 0xe6, 0xc3, 0x08, 0xc5, 0x4d, 0x53, 0x93,
+	0x9a, 0xbd, 0xb8, 0xc8, 0xe9, 0xe7, 0xe4, 0xf6, 0x71, 0x36, 0x1c, 0x8e, 0x1b, 0x95, 0xb0, 0xa3,
+	0xeb, 0x81, 0x8a, 0xb5, 0xd9, 0x02, 0xa7, 0x0f, 0xc9, 0xdc, 0x38, 0xa4, 0x1a, 0x37, 0x66, 0x02,
+	0x65, 0x21, 0x50, 0x8a, 0xf4, 0xb6, 0xbc, 0x6b, 0x3d, 0x3c, 0xfc, 0xe2, 0x64, 0x90, 0x9f, 0x4e,
+	0x8e, 0x36, 0x8f, 0x2f, 0xce, 0xb6, 0xae, 0xae, 0x26, 0xfd, 0xd7, 0x83, 0xfe, 0x56, 0x76, 0x3e,
+	0x38, 0xcb, 0x4e, 0x26, 0xe3, 0xad, 0xcb, 0x37, 0x27, 0x5b, 0xd9, 0x38, 0x3f, 0x9a, 0x0d, 0xbf,
+	0x9f, 0x47, 0xff, 0x0d, 0x00, 0x96, 0x0a, 0x81, 0x85, 0x8b, 0x06, 0x00, 0x00,
 }
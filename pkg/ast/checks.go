@@ -0,0 +1,108 @@
+package ast
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Checks that can be enabled on a TRANSACTION value by setting the bits
+// in its u field.
+const (
+	CheckOccupiedCapacity uint64 = 1 << iota
+	// CheckConsensus runs the subset of CKB's transaction verification rules
+	// that can be done without chain state, it also implies
+	// CheckOccupiedCapacity.
+	CheckConsensus
+)
+
+func CheckTransaction(value *Value, checks uint64) error {
+	if checks&CheckConsensus != 0 {
+		checks |= CheckOccupiedCapacity
+		if err := checkConsensus(value); err != nil {
+			return err
+		}
+	}
+	if checks&CheckOccupiedCapacity != 0 {
+		for i, output := range value.GetChildren()[1].GetChildren() {
+			if err := IsValidCellCapacity(output); err != nil {
+				return fmt.Errorf("Output %d: %s", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkConsensus(value *Value) error {
+	inputs := value.GetChildren()[0].GetChildren()
+	outputs := value.GetChildren()[1].GetChildren()
+	if len(inputs) == 0 {
+		return fmt.Errorf("Transaction has no inputs!")
+	}
+	if len(outputs) == 0 {
+		return fmt.Errorf("Transaction has no outputs!")
+	}
+	if err := checkDuplicates("input", inputs, func(v *Value) *Value {
+		return v.GetChildren()[0]
+	}); err != nil {
+		return err
+	}
+	if err := checkDuplicates("cell dep", value.GetChildren()[2].GetChildren(), func(v *Value) *Value {
+		return v
+	}); err != nil {
+		return err
+	}
+	if len(value.GetChildren()) > 3 {
+		if err := checkDuplicates("header dep", value.GetChildren()[3].GetChildren(), func(v *Value) *Value {
+			return v
+		}); err != nil {
+			return err
+		}
+	}
+	// Outputs data are kept in output cells, an output without valid data
+	// would not have a matching outputs data entry.
+	for i, output := range outputs {
+		if err := IsValidCell(output); err != nil {
+			return fmt.Errorf("Output %d: %s", i, err)
+		}
+	}
+
+	var inputCapacity, outputCapacity uint64
+	for i, input := range inputs {
+		if len(input.GetChildren()) < 3 {
+			return fmt.Errorf("Input %d does not have a resolved cell, capacity cannot be checked!", i)
+		}
+		capacity := input.GetChildren()[2].GetChildren()[0].GetU()
+		if inputCapacity > math.MaxUint64-capacity {
+			return fmt.Errorf("Input capacity overflow!")
+		}
+		inputCapacity += capacity
+	}
+	for _, output := range outputs {
+		capacity := output.GetChildren()[0].GetU()
+		if outputCapacity > math.MaxUint64-capacity {
+			return fmt.Errorf("Output capacity overflow!")
+		}
+		outputCapacity += capacity
+	}
+	if inputCapacity < outputCapacity {
+		return fmt.Errorf("Output capacity %d exceeds input capacity %d!", outputCapacity, inputCapacity)
+	}
+	return nil
+}
+
+func checkDuplicates(name string, values []*Value, key func(*Value) *Value) error {
+	found := make(map[string]int)
+	for i, value := range values {
+		serialized, err := proto.Marshal(key(value))
+		if err != nil {
+			return err
+		}
+		if j, ok := found[string(serialized)]; ok {
+			return fmt.Errorf("Duplicate %s found at %d and %d!", name, j, i)
+		}
+		found[string(serialized)] = i
+	}
+	return nil
+}
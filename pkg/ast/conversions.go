@@ -154,6 +154,9 @@ func ConvertCell(cell rpctypes.CellOutput, cellData rpctypes.Raw,
 		},
 		ConvertOutPoint(outPoint),
 	}
+	if header != nil {
+		children = append(children, ConvertHeader(*header))
+	}
 	return &Value{
 		T:        Value_CELL,
 		Children: children,
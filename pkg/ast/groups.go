@@ -0,0 +1,37 @@
+package ast
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+type LockGroup struct {
+	// Lock is nil when the input does not carry its resolved cell, such
+	// input always forms a group by itself.
+	Lock    *Value
+	Indices []int
+}
+
+// LockGroups groups inputs of an evaluated TRANSACTION by lock script, in
+// the order each lock first appears.
+func LockGroups(value *Value) []LockGroup {
+	groups := make([]LockGroup, 0)
+	for i, input := range value.GetChildren()[0].GetChildren() {
+		if len(input.GetChildren()) < 3 {
+			groups = append(groups, LockGroup{Indices: []int{i}})
+			continue
+		}
+		lock := input.GetChildren()[2].GetChildren()[1]
+		found := false
+		for j := range groups {
+			if groups[j].Lock != nil && proto.Equal(groups[j].Lock, lock) {
+				groups[j].Indices = append(groups[j].Indices, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, LockGroup{Lock: lock, Indices: []int{i}})
+		}
+	}
+	return groups
+}
@@ -1,6 +1,8 @@
 package ast
 
 import (
+	"math/big"
+
 	"github.com/xxuejie/animagus/pkg/rpctypes"
 )
 
@@ -12,11 +14,7 @@ func RestoreScript(value *Value, validate bool) (result rpctypes.Script, err err
 		}
 	}
 	copy(result.CodeHash[:], value.GetChildren()[0].GetRaw())
-	if value.GetChildren()[1].GetU() == 1 {
-		result.HashType = rpctypes.Type
-	} else {
-		result.HashType = rpctypes.Data
-	}
+	result.HashType = rpctypes.ScriptHashType(value.GetChildren()[1].GetU())
 	result.Args = make([]byte, len(value.GetChildren()[2].GetRaw()))
 	copy(result.Args, value.GetChildren()[2].GetRaw())
 	return
@@ -101,6 +99,33 @@ func RestoreCellDep(value *Value, validate bool) (cellDep rpctypes.CellDep, err
 	return
 }
 
+func RestoreHeader(value *Value, validate bool) (header rpctypes.Header, err error) {
+	if validate {
+		err = IsValidHeader(value)
+		if err != nil {
+			return
+		}
+	}
+	children := value.GetChildren()
+	header.CompactTarget = rpctypes.Uint32(children[0].GetU())
+	header.Timestamp = rpctypes.Uint64(children[1].GetU())
+	header.Number = rpctypes.Uint64(children[2].GetU())
+	header.Epoch = rpctypes.Uint64(children[3].GetU())
+	copy(header.ParentHash[:], children[4].GetRaw())
+	copy(header.TransactionsRoot[:], children[5].GetRaw())
+	copy(header.ProposalsHash[:], children[6].GetRaw())
+	copy(header.UnclesHash[:], children[7].GetRaw())
+	header.Dao = make([]byte, len(children[8].GetRaw()))
+	copy(header.Dao, children[8].GetRaw())
+	// Nonce is kept in little endian format in AST values
+	nonce := make([]byte, len(children[9].GetRaw()))
+	for i, b := range children[9].GetRaw() {
+		nonce[len(nonce)-1-i] = b
+	}
+	header.Nonce.V = new(big.Int).SetBytes(nonce)
+	return
+}
+
 func RestoreTransaction(value *Value, validate bool) (tx rpctypes.Transaction, err error) {
 	if validate {
 		err = IsValidTransaction(value)
@@ -136,5 +161,25 @@ func RestoreTransaction(value *Value, validate bool) (tx rpctypes.Transaction, e
 		}
 		tx.CellDeps = append(tx.CellDeps, restoredDep)
 	}
+	if len(value.GetChildren()) > 3 {
+		for _, headerDep := range value.GetChildren()[3].GetChildren() {
+			var h rpctypes.Hash
+			copy(h[:], headerDep.GetRaw())
+			tx.HeaderDeps = append(tx.HeaderDeps, h)
+		}
+	}
+	if len(value.GetChildren()) > 4 {
+		// Witnesses not provided in the AST are kept as empty bytes, so each
+		// input still has its own witness slot.
+		for i, witness := range value.GetChildren()[4].GetChildren() {
+			w := make([]byte, len(witness.GetRaw()))
+			copy(w, witness.GetRaw())
+			if i < len(tx.Witnesses) {
+				tx.Witnesses[i] = w
+			} else {
+				tx.Witnesses = append(tx.Witnesses, w)
+			}
+		}
+	}
 	return
 }
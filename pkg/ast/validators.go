@@ -3,6 +3,8 @@ package ast
 import (
 	"fmt"
 	"math"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
 )
 
 func IsValidScript(value *Value) error {
@@ -15,7 +17,7 @@ func IsValidScript(value *Value) error {
 	if value.GetChildren()[0].GetT() != Value_BYTES ||
 		len(value.GetChildren()[0].GetRaw()) != 32 ||
 		value.GetChildren()[1].GetT() != Value_UINT64 ||
-		(value.GetChildren()[1].GetU() != 0 && value.GetChildren()[1].GetU() != 1) ||
+		value.GetChildren()[1].GetU() > uint64(rpctypes.Data1) ||
 		value.GetChildren()[2].GetT() != Value_BYTES {
 		return fmt.Errorf("Invalid child type!")
 	}
@@ -66,8 +68,8 @@ func IsValidCell(value *Value) error {
 		return fmt.Errorf("Invalid cell!")
 	}
 	l := len(value.GetChildren())
-	if l != 4 && l != 6 {
-		return fmt.Errorf("Invalid number of out point items!")
+	if l < 4 || l > 6 {
+		return fmt.Errorf("Invalid number of cell items!")
 	}
 	if value.GetChildren()[0].GetT() != Value_UINT64 ||
 		(IsValidScript(value.GetChildren()[1]) != nil) ||
@@ -75,10 +77,12 @@ func IsValidCell(value *Value) error {
 		value.GetChildren()[3].GetT() != Value_BYTES {
 		return fmt.Errorf("Invalid child type")
 	}
-	if l == 6 {
+	if l > 4 {
 		if err := IsValidOutPoint(value.GetChildren()[4]); err != nil {
 			return err
 		}
+	}
+	if l > 5 {
 		if err := IsValidHeader(value.GetChildren()[5]); err != nil {
 			return err
 		}
@@ -86,17 +90,44 @@ func IsValidCell(value *Value) error {
 	return nil
 }
 
+func OccupiedCapacity(value *Value) (uint64, error) {
+	cell, cellData, _, err := RestoreCell(value, true)
+	if err != nil {
+		return 0, err
+	}
+	return cell.OccupiedCapacity(uint64(len(cellData)))
+}
+
+func IsValidCellCapacity(value *Value) error {
+	occupied, err := OccupiedCapacity(value)
+	if err != nil {
+		return err
+	}
+	capacity := value.GetChildren()[0].GetU()
+	if capacity < occupied {
+		return fmt.Errorf("Cell capacity %d is less than occupied capacity %d!", capacity, occupied)
+	}
+	return nil
+}
+
 func IsValidCellInput(value *Value) error {
 	if value.GetT() != Value_CELL_INPUT {
 		return fmt.Errorf("Invalid cell input!")
 	}
-	if len(value.GetChildren()) != 2 {
+	l := len(value.GetChildren())
+	if l != 2 && l != 3 {
 		return fmt.Errorf("Invalid number of cell input items")
 	}
 	if IsValidOutPoint(value.GetChildren()[0]) != nil ||
 		value.GetChildren()[1].GetT() != Value_UINT64 {
 		return fmt.Errorf("Invalid child type")
 	}
+	// The optional third item keeps the cell consumed by this input
+	if l == 3 {
+		if err := IsValidCell(value.GetChildren()[2]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -119,13 +150,14 @@ func IsValidTransaction(value *Value) error {
 	if value.GetT() != Value_TRANSACTION {
 		return fmt.Errorf("Invalid transaction!")
 	}
-	if len(value.GetChildren()) != 3 {
+	l := len(value.GetChildren())
+	if l < 3 || l > 5 {
 		return fmt.Errorf("Invalid number of transaction items")
 	}
-	if value.GetChildren()[0].GetT() != Value_LIST ||
-		value.GetChildren()[1].GetT() != Value_LIST ||
-		value.GetChildren()[2].GetT() != Value_LIST {
-		return fmt.Errorf("Invalid child type")
+	for _, child := range value.GetChildren() {
+		if child.GetT() != Value_LIST {
+			return fmt.Errorf("Invalid child type")
+		}
 	}
 	for _, child := range value.GetChildren()[0].GetChildren() {
 		if err := IsValidCellInput(child); err != nil {
@@ -142,6 +174,20 @@ func IsValidTransaction(value *Value) error {
 			return err
 		}
 	}
+	if l > 3 {
+		for _, child := range value.GetChildren()[3].GetChildren() {
+			if err := isValidBytes(child, 32); err != nil {
+				return err
+			}
+		}
+	}
+	if l > 4 {
+		for _, child := range value.GetChildren()[4].GetChildren() {
+			if child.GetT() != Value_BYTES {
+				return fmt.Errorf("Invalid witness type")
+			}
+		}
+	}
 	return nil
 }
 
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/big"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/xxuejie/animagus/pkg/address"
 	"github.com/xxuejie/animagus/pkg/ast"
 	"github.com/xxuejie/animagus/pkg/rpctypes"
 )
@@ -152,21 +154,21 @@ func evaluateValueNonRecursion(expr *ast.Value, e Environment) (*ast.Value, erro
 		}
 		inputs := make([]*ast.Value, len(inputCells))
 		for i, inputCell := range inputCells {
-			if inputCell.GetT() != ast.Value_CELL ||
-				len(inputCell.GetChildren()) < 5 {
-				return nil, fmt.Errorf("Invalid input cell!")
-			}
-			inputs[i] = &ast.Value{
-				T: ast.Value_CELL_INPUT,
-				Children: []*ast.Value{
-					inputCell.GetChildren()[4],
-					&ast.Value{
-						T: ast.Value_UINT64,
-						Primitive: &ast.Value_U{
-							U: 0,
-						},
+			switch inputCell.GetT() {
+			case ast.Value_CELL:
+				inputs[i], err = cellToCellInput(inputCell, &ast.Value{
+					T: ast.Value_UINT64,
+					Primitive: &ast.Value_U{
+						U: 0,
 					},
-				},
+				})
+				if err != nil {
+					return nil, err
+				}
+			case ast.Value_CELL_INPUT:
+				inputs[i] = inputCell
+			default:
+				return nil, fmt.Errorf("Invalid input type: %s", inputCell.GetT().String())
 			}
 		}
 		outputs, err := evaluateValueNonRecursion(expr.GetChildren()[1], e)
@@ -202,8 +204,37 @@ func evaluateValueNonRecursion(expr *ast.Value, e Environment) (*ast.Value, erro
 				return nil, fmt.Errorf("Invalid dep type: %s", depValue.GetT().String())
 			}
 		}
+		headerDeps := make([]*ast.Value, 0)
+		witnesses := make([]*ast.Value, 0)
+		if len(expr.GetChildren()) > 3 {
+			headerValues, err := evaluateList(expr.GetChildren()[3], e)
+			if err != nil {
+				return nil, err
+			}
+			for _, headerValue := range headerValues {
+				switch headerValue.GetT() {
+				case ast.Value_BYTES:
+					headerDeps = append(headerDeps, headerValue)
+				case ast.Value_HEADER:
+					headerHash, err := evaluateHash(headerValue)
+					if err != nil {
+						return nil, err
+					}
+					headerDeps = append(headerDeps, headerHash)
+				default:
+					return nil, fmt.Errorf("Invalid header dep type: %s", headerValue.GetT().String())
+				}
+			}
+		}
+		if len(expr.GetChildren()) > 4 {
+			witnesses, err = evaluateList(expr.GetChildren()[4], e)
+			if err != nil {
+				return nil, err
+			}
+		}
 		tx := &ast.Value{
-			T: ast.Value_TRANSACTION,
+			T:         ast.Value_TRANSACTION,
+			Primitive: expr.GetPrimitive(),
 			Children: []*ast.Value{
 				&ast.Value{
 					T:        ast.Value_LIST,
@@ -214,12 +245,24 @@ func evaluateValueNonRecursion(expr *ast.Value, e Environment) (*ast.Value, erro
 					T:        ast.Value_LIST,
 					Children: deps,
 				},
+				&ast.Value{
+					T:        ast.Value_LIST,
+					Children: headerDeps,
+				},
+				&ast.Value{
+					T:        ast.Value_LIST,
+					Children: witnesses,
+				},
 			},
 		}
 		err = ast.IsValidTransaction(tx)
 		if err != nil {
 			return nil, err
 		}
+		err = ast.CheckTransaction(tx, tx.GetU())
+		if err != nil {
+			return nil, err
+		}
 		return tx, nil
 	case ast.Value_CELL:
 		value, err := evaluateChildren(expr, e)
@@ -241,6 +284,38 @@ func evaluateValueNonRecursion(expr *ast.Value, e Environment) (*ast.Value, erro
 			return nil, err
 		}
 		return value, nil
+	case ast.Value_CELL_INPUT:
+		value, err := evaluateChildren(expr, e)
+		if err != nil {
+			return nil, err
+		}
+		// A cell input can be built from a full cell, in which case the cell
+		// is kept as the last item for later transaction checks.
+		if value.GetChildren()[0].GetT() == ast.Value_CELL {
+			value, err = cellToCellInput(value.GetChildren()[0], value.GetChildren()[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = ast.IsValidCellInput(value)
+		if err != nil {
+			return nil, err
+		}
+		err = rpctypes.VerifySince(value.GetChildren()[1].GetU())
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	case ast.Value_HEADER:
+		value, err := evaluateChildren(expr, e)
+		if err != nil {
+			return nil, err
+		}
+		err = ast.IsValidHeader(value)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
 	case ast.Value_CELL_DEP:
 		value, err := evaluateChildren(expr, e)
 		if err != nil {
@@ -297,6 +372,21 @@ func evaluateValueNonRecursion(expr *ast.Value, e Environment) (*ast.Value, erro
 	return nil, fmt.Errorf("Invalid value type: %s", expr.GetT().String())
 }
 
+func cellToCellInput(cell *ast.Value, since *ast.Value) (*ast.Value, error) {
+	if cell.GetT() != ast.Value_CELL ||
+		len(cell.GetChildren()) < 5 {
+		return nil, fmt.Errorf("Invalid input cell!")
+	}
+	return &ast.Value{
+		T: ast.Value_CELL_INPUT,
+		Children: []*ast.Value{
+			cell.GetChildren()[4],
+			since,
+			cell,
+		},
+	}, nil
+}
+
 func evaluateChildren(value *ast.Value, e Environment) (*ast.Value, error) {
 	children, err := evaluateAstValues(value.GetChildren(), e)
 	if err != nil {
@@ -568,6 +658,161 @@ func evaluateOp(op ast.Value_Type, operands []*ast.Value, e Environment) (*ast.V
 				U: uint64(len(operands[0].GetRaw())),
 			},
 		}, nil
+	case ast.Value_DECODE_ADDRESS:
+		return evaluateDecodeAddress(operands)
+	case ast.Value_ENCODE_ADDRESS:
+		return evaluateEncodeAddress(operands[0], operands[1])
+	case ast.Value_DECODE_DAO:
+		if operands[0].GetT() != ast.Value_BYTES {
+			return nil, fmt.Errorf("Invalid operand type to DECODE_DAO")
+		}
+		dao, err := rpctypes.ParseDaoField(operands[0].GetRaw())
+		if err != nil {
+			return nil, err
+		}
+		return uint64List(dao.C, dao.AR, dao.S, dao.U), nil
+	case ast.Value_DECODE_EPOCH:
+		if operands[0].GetT() != ast.Value_UINT64 {
+			return nil, fmt.Errorf("Invalid operand type to DECODE_EPOCH")
+		}
+		epoch := rpctypes.EpochNumberWithFraction(operands[0].GetU())
+		return uint64List(epoch.Number(), epoch.Index(), epoch.Length()), nil
+	case ast.Value_DAO_MAXIMUM_WITHDRAW:
+		return evaluateDaoMaximumWithdraw(operands[0], operands[1], operands[2])
+	case ast.Value_ENCODE_SINCE:
+		if operands[0].GetT() != ast.Value_UINT64 ||
+			operands[1].GetT() != ast.Value_BOOL ||
+			operands[2].GetT() != ast.Value_UINT64 {
+			return nil, fmt.Errorf("Invalid operand type to ENCODE_SINCE")
+		}
+		since, err := rpctypes.EncodeSince(operands[0].GetU(), operands[1].GetB(), operands[2].GetU())
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Value{
+			T: ast.Value_UINT64,
+			Primitive: &ast.Value_U{
+				U: since,
+			},
+		}, nil
+	case ast.Value_OCCUPIED_CAPACITY:
+		capacity, err := ast.OccupiedCapacity(operands[0])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Value{
+			T: ast.Value_UINT64,
+			Primitive: &ast.Value_U{
+				U: capacity,
+			},
+		}, nil
+	case ast.Value_FEE:
+		fee, err := evaluateFee(operands[0], operands[1], operands[2:])
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Value{
+			T: ast.Value_UINT64,
+			Primitive: &ast.Value_U{
+				U: fee,
+			},
+		}, nil
+	case ast.Value_DEDUCT_FEE:
+		return evaluateDeductFee(operands[0], operands[1], operands[2], operands[3:])
+	case ast.Value_SIGNING_ENTRIES:
+		return evaluateSigningEntries(operands[0], operands[1:])
+	case ast.Value_SIGNING_REQUEST:
+		return evaluateSigningRequest(operands[0], operands[1:])
+	case ast.Value_MULTISIG_LOCK_ARGS:
+		config, err := multisigConfig(operands)
+		if err != nil {
+			return nil, err
+		}
+		var since *uint64
+		if len(operands) > 3 {
+			if operands[3].GetT() != ast.Value_UINT64 {
+				return nil, fmt.Errorf("Invalid operand type to MULTISIG_LOCK_ARGS")
+			}
+			s := operands[3].GetU()
+			since = &s
+		}
+		args, err := config.LockArgs(since)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Value{
+			T: ast.Value_BYTES,
+			Primitive: &ast.Value_Raw{
+				Raw: args,
+			},
+		}, nil
+	case ast.Value_MULTISIG_WITNESS:
+		config, err := multisigConfig(operands)
+		if err != nil {
+			return nil, err
+		}
+		lock, err := config.WitnessLock()
+		if err != nil {
+			return nil, err
+		}
+		witness, err := rpctypes.WitnessWithLock(nil, lock)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Value{
+			T: ast.Value_BYTES,
+			Primitive: &ast.Value_Raw{
+				Raw: witness,
+			},
+		}, nil
+	case ast.Value_ACP_LOCK:
+		return evaluateAcpLock(operands)
+	case ast.Value_IS_ACP_LOCK:
+		script, err := ast.RestoreScript(operands[0], true)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Value{
+			T: ast.Value_BOOL,
+			Primitive: &ast.Value_B{
+				B: address.IsAnyoneCanPayLock(script),
+			},
+		}, nil
+	case ast.Value_ACP_TOP_UP:
+		return evaluateAcpTopUp(operands[0], operands[1], operands[2:])
+	case ast.Value_TYPE_ID:
+		return evaluateTypeID(operands[0], operands[1])
+	case ast.Value_DECODE_SINCE:
+		if operands[0].GetT() != ast.Value_UINT64 {
+			return nil, fmt.Errorf("Invalid operand type to DECODE_SINCE")
+		}
+		metric, relative, value, err := rpctypes.DecodeSince(operands[0].GetU())
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Value{
+			T: ast.Value_LIST,
+			Children: []*ast.Value{
+				&ast.Value{
+					T: ast.Value_UINT64,
+					Primitive: &ast.Value_U{
+						U: metric,
+					},
+				},
+				&ast.Value{
+					T: ast.Value_BOOL,
+					Primitive: &ast.Value_B{
+						B: relative,
+					},
+				},
+				&ast.Value{
+					T: ast.Value_UINT64,
+					Primitive: &ast.Value_U{
+						U: value,
+					},
+				},
+			},
+		}, nil
 	}
 	return nil, fmt.Errorf("Invalid op: %s", op.String())
 }
@@ -715,6 +960,21 @@ func evaluateHash(value *ast.Value) (*ast.Value, error) {
 				Raw: h,
 			},
 		}, nil
+	case ast.Value_HEADER:
+		header, err := ast.RestoreHeader(value, true)
+		if err != nil {
+			return nil, err
+		}
+		h, err := rpctypes.CalculateHash(header)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Value{
+			T: ast.Value_BYTES,
+			Primitive: &ast.Value_Raw{
+				Raw: h,
+			},
+		}, nil
 	}
 	return nil, fmt.Errorf("Invalid value type: %s, cannot calculate hash", value.GetT().String())
 }
@@ -722,6 +982,9 @@ func evaluateHash(value *ast.Value) (*ast.Value, error) {
 func evaluateSerialize(value *ast.Value, toJson bool) (*ast.Value, error) {
 	switch value.GetT() {
 	case ast.Value_TRANSACTION:
+		if err := ast.CheckTransaction(value, value.GetU()); err != nil {
+			return nil, err
+		}
 		tx, err := ast.RestoreTransaction(value, true)
 		if err != nil {
 			return nil, err
@@ -750,6 +1013,434 @@ func evaluateSerialize(value *ast.Value, toJson bool) (*ast.Value, error) {
 	return nil, fmt.Errorf("Invalid value type: %s", value.GetT().String())
 }
 
+func evaluateDecodeAddress(operands []*ast.Value) (*ast.Value, error) {
+	if operands[0].GetT() != ast.Value_BYTES {
+		return nil, fmt.Errorf("Invalid operand type to DECODE_ADDRESS")
+	}
+	prefix, script, err := address.Parse(string(operands[0].GetRaw()))
+	if err != nil {
+		return nil, err
+	}
+	// An optional second operand pins the address to a network prefix, so a
+	// testnet address will not be accepted by a mainnet call by accident.
+	if len(operands) > 1 {
+		if operands[1].GetT() != ast.Value_BYTES {
+			return nil, fmt.Errorf("Invalid operand type to DECODE_ADDRESS")
+		}
+		if string(operands[1].GetRaw()) != prefix {
+			return nil, fmt.Errorf("Invalid address prefix: %s, expected: %s", prefix, operands[1].GetRaw())
+		}
+	}
+	return ast.ConvertScript(script), nil
+}
+
+func evaluateEncodeAddress(value *ast.Value, prefix *ast.Value) (*ast.Value, error) {
+	if prefix.GetT() != ast.Value_BYTES {
+		return nil, fmt.Errorf("Invalid operand type to ENCODE_ADDRESS")
+	}
+	script, err := ast.RestoreScript(value, true)
+	if err != nil {
+		return nil, err
+	}
+	a, err := address.Generate(string(prefix.GetRaw()), script)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Value{
+		T: ast.Value_BYTES,
+		Primitive: &ast.Value_Raw{
+			Raw: []byte(a),
+		},
+	}, nil
+}
+
+func evaluateDaoMaximumWithdraw(cell *ast.Value, depositHeader *ast.Value, withdrawingHeader *ast.Value) (*ast.Value, error) {
+	output, data, _, err := ast.RestoreCell(cell, true)
+	if err != nil {
+		return nil, err
+	}
+	if ast.IsValidHeader(depositHeader) != nil ||
+		ast.IsValidHeader(withdrawingHeader) != nil {
+		return nil, fmt.Errorf("Invalid header operand to DAO_MAXIMUM_WITHDRAW")
+	}
+	depositDao, err := rpctypes.ParseDaoField(depositHeader.GetChildren()[8].GetRaw())
+	if err != nil {
+		return nil, err
+	}
+	withdrawingDao, err := rpctypes.ParseDaoField(withdrawingHeader.GetChildren()[8].GetRaw())
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := rpctypes.CalculateMaximumWithdraw(output, uint64(len(data)), depositDao, withdrawingDao)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Value{
+		T: ast.Value_UINT64,
+		Primitive: &ast.Value_U{
+			U: capacity,
+		},
+	}, nil
+}
+
+// evaluateFee calculates fee using the size of the transaction after signing,
+// the first witness in each lock script group is replaced by the signing
+// witness, reserving a lock of the given size, 65 bytes for secp256k1
+// signatures by default. Multisig groups use the size of the placeholder
+// built by MULTISIG_WITNESS instead.
+func evaluateFee(value *ast.Value, feeRate *ast.Value, rest []*ast.Value) (uint64, error) {
+	if value.GetT() != ast.Value_TRANSACTION ||
+		feeRate.GetT() != ast.Value_UINT64 {
+		return 0, fmt.Errorf("Invalid operand type to FEE")
+	}
+	lockSize := rpctypes.Secp256k1SignatureSize
+	if len(rest) > 0 {
+		if rest[0].GetT() != ast.Value_UINT64 {
+			return 0, fmt.Errorf("Invalid operand type to FEE")
+		}
+		lockSize = rest[0].GetU()
+	}
+	tx, err := ast.RestoreTransaction(value, true)
+	if err != nil {
+		return 0, err
+	}
+	for _, group := range ast.LockGroups(value) {
+		i := group.Indices[0]
+		witness, err := signingWitness(group, tx.Witnesses[i], lockSize)
+		if err != nil {
+			return 0, fmt.Errorf("Witness %d: %s", i, err)
+		}
+		tx.Witnesses[i] = witness
+	}
+	size, err := tx.SerializedSizeInBlock()
+	if err != nil {
+		return 0, err
+	}
+	return rpctypes.CalculateFee(size, feeRate.GetU())
+}
+
+func evaluateDeductFee(value *ast.Value, feeRate *ast.Value, changeIndex *ast.Value, rest []*ast.Value) (*ast.Value, error) {
+	if changeIndex.GetT() != ast.Value_UINT64 {
+		return nil, fmt.Errorf("Invalid operand type to DEDUCT_FEE")
+	}
+	fee, err := evaluateFee(value, feeRate, rest)
+	if err != nil {
+		return nil, err
+	}
+	outputs := value.GetChildren()[1].GetChildren()
+	if changeIndex.GetU() >= uint64(len(outputs)) {
+		return nil, fmt.Errorf("Change output index %d is out of bound!", changeIndex.GetU())
+	}
+	change := outputs[changeIndex.GetU()]
+	capacity := change.GetChildren()[0].GetU()
+	if capacity < fee {
+		return nil, fmt.Errorf("Change output capacity %d is not enough to pay fee %d!", capacity, fee)
+	}
+	// Capacity is serialized as fixed size integer, deducting the fee will
+	// not change transaction size, hence the fee calculated above.
+	changeChildren := make([]*ast.Value, len(change.GetChildren()))
+	copy(changeChildren, change.GetChildren())
+	changeChildren[0] = &ast.Value{
+		T: ast.Value_UINT64,
+		Primitive: &ast.Value_U{
+			U: capacity - fee,
+		},
+	}
+	adjustedChange := &ast.Value{
+		T:        ast.Value_CELL,
+		Children: changeChildren,
+	}
+	if err := ast.IsValidCellCapacity(adjustedChange); err != nil {
+		return nil, fmt.Errorf("Change output: %s", err)
+	}
+	adjustedOutputs := make([]*ast.Value, len(outputs))
+	copy(adjustedOutputs, outputs)
+	adjustedOutputs[changeIndex.GetU()] = adjustedChange
+	children := make([]*ast.Value, len(value.GetChildren()))
+	copy(children, value.GetChildren())
+	children[1] = &ast.Value{
+		T:        ast.Value_LIST,
+		Children: adjustedOutputs,
+	}
+	tx := &ast.Value{
+		T:         ast.Value_TRANSACTION,
+		Primitive: value.GetPrimitive(),
+		Children:  children,
+	}
+	if err := ast.CheckTransaction(tx, tx.GetU()); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// evaluateSigningEntries generates one entry per lock script group in the
+// form of [lock, input indices, signing message].
+func evaluateSigningEntries(value *ast.Value, rest []*ast.Value) (*ast.Value, error) {
+	if value.GetT() != ast.Value_TRANSACTION {
+		return nil, fmt.Errorf("Invalid operand type to SIGNING_ENTRIES")
+	}
+	lockSize := rpctypes.Secp256k1SignatureSize
+	if len(rest) > 0 {
+		if rest[0].GetT() != ast.Value_UINT64 {
+			return nil, fmt.Errorf("Invalid operand type to SIGNING_ENTRIES")
+		}
+		lockSize = rest[0].GetU()
+	}
+	tx, err := ast.RestoreTransaction(value, true)
+	if err != nil {
+		return nil, err
+	}
+	groups := ast.LockGroups(value)
+	entries := make([]*ast.Value, len(groups))
+	for i, group := range groups {
+		if group.Lock == nil {
+			return nil, fmt.Errorf("Input %d does not have a resolved cell, its lock is unknown!", group.Indices[0])
+		}
+		first, err := signingWitness(group, tx.Witnesses[group.Indices[0]], lockSize)
+		if err != nil {
+			return nil, fmt.Errorf("Witness %d: %s", group.Indices[0], err)
+		}
+		message, err := tx.SigningMessageWithWitness(group.Indices, first)
+		if err != nil {
+			return nil, err
+		}
+		indices := make([]uint64, len(group.Indices))
+		for j, index := range group.Indices {
+			indices[j] = uint64(index)
+		}
+		entries[i] = &ast.Value{
+			T: ast.Value_LIST,
+			Children: []*ast.Value{
+				group.Lock,
+				uint64List(indices...),
+				&ast.Value{
+					T: ast.Value_BYTES,
+					Primitive: &ast.Value_Raw{
+						Raw: message,
+					},
+				},
+			},
+		}
+	}
+	return &ast.Value{
+		T:        ast.Value_LIST,
+		Children: entries,
+	}, nil
+}
+
+// evaluateSigningRequest returns the JSON serialized transaction with its
+// signing entries.
+func evaluateSigningRequest(value *ast.Value, rest []*ast.Value) (*ast.Value, error) {
+	serialized, err := evaluateSerialize(value, true)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := evaluateSigningEntries(value, rest)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Value{
+		T:        ast.Value_LIST,
+		Children: []*ast.Value{serialized, entries},
+	}, nil
+}
+
+// signingWitness prepares the first witness of a lock group for fee and
+// signing message calculation.
+func signingWitness(group ast.LockGroup, witness []byte, lockSize uint64) ([]byte, error) {
+	if group.Lock != nil &&
+		bytes.Equal(group.Lock.GetChildren()[0].GetRaw(), address.Secp256k1MultisigTypeHash[:]) &&
+		group.Lock.GetChildren()[1].GetU() == uint64(rpctypes.Type) {
+		return rpctypes.MultisigSigningWitness(witness)
+	}
+	return rpctypes.SigningWitness(witness, lockSize)
+}
+
+func multisigConfig(operands []*ast.Value) (rpctypes.MultisigConfig, error) {
+	if operands[0].GetT() != ast.Value_LIST ||
+		operands[1].GetT() != ast.Value_UINT64 ||
+		operands[2].GetT() != ast.Value_UINT64 {
+		return rpctypes.MultisigConfig{}, fmt.Errorf("Invalid operand type to multisig operations")
+	}
+	hashes := make([][]byte, len(operands[0].GetChildren()))
+	for i, h := range operands[0].GetChildren() {
+		if h.GetT() != ast.Value_BYTES {
+			return rpctypes.MultisigConfig{}, fmt.Errorf("Invalid pubkey hash type: %s", h.GetT().String())
+		}
+		hashes[i] = h.GetRaw()
+	}
+	config := rpctypes.MultisigConfig{
+		Threshold:     operands[1].GetU(),
+		RequireFirstN: operands[2].GetU(),
+		PubkeyHashes:  hashes,
+	}
+	return config, config.Verify()
+}
+
+func evaluateAcpLock(operands []*ast.Value) (*ast.Value, error) {
+	if operands[0].GetT() != ast.Value_BYTES ||
+		operands[1].GetT() != ast.Value_BYTES {
+		return nil, fmt.Errorf("Invalid operand type to ACP_LOCK")
+	}
+	args := address.AnyoneCanPayArgs{
+		PubkeyHash: operands[1].GetRaw(),
+	}
+	minimums := make([]*uint8, 0)
+	for _, operand := range operands[2:] {
+		if operand.GetT() != ast.Value_UINT64 || operand.GetU() > 255 {
+			return nil, fmt.Errorf("Invalid minimum amount to ACP_LOCK")
+		}
+		minimum := uint8(operand.GetU())
+		minimums = append(minimums, &minimum)
+	}
+	if len(minimums) > 0 {
+		args.MinimumCKB = minimums[0]
+	}
+	if len(minimums) > 1 {
+		args.MinimumUDT = minimums[1]
+	}
+	script, err := address.AnyoneCanPayLock(string(operands[0].GetRaw()), args)
+	if err != nil {
+		return nil, err
+	}
+	return ast.ConvertScript(script), nil
+}
+
+// evaluateAcpTopUp builds the output of an anyone-can-pay cell consumed as
+// input, with capacity and optionally UDT amount increased.
+func evaluateAcpTopUp(cell *ast.Value, capacity *ast.Value, rest []*ast.Value) (*ast.Value, error) {
+	if capacity.GetT() != ast.Value_UINT64 {
+		return nil, fmt.Errorf("Invalid operand type to ACP_TOP_UP")
+	}
+	output, data, _, err := ast.RestoreCell(cell, true)
+	if err != nil {
+		return nil, err
+	}
+	acpArgs, err := address.ParseAnyoneCanPayArgs(output.Lock)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(output.Capacity) > math.MaxUint64-capacity.GetU() {
+		return nil, fmt.Errorf("Capacity overflow!")
+	}
+	newData := cell.GetChildren()[3]
+	var udtAmount *big.Int
+	if len(rest) > 0 {
+		udtAmount, err = valueToBigInt(rest[0])
+		if err != nil {
+			return nil, err
+		}
+		if output.Type == nil || len(data) < 16 {
+			return nil, fmt.Errorf("Anyone-can-pay cell does not hold UDT!")
+		}
+		amount, err := valueToBigInt(&ast.Value{
+			T: ast.Value_BYTES,
+			Primitive: &ast.Value_Raw{
+				Raw: data[0:16],
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		amount.Add(amount, udtAmount)
+		if amount.BitLen() > 128 {
+			return nil, fmt.Errorf("UDT amount overflow!")
+		}
+		raw := make([]byte, len(data))
+		copy(raw, data)
+		for i := range raw[0:16] {
+			raw[i] = 0
+		}
+		copy(raw[0:16], bigIntToValue(amount).GetRaw())
+		newData = &ast.Value{
+			T: ast.Value_BYTES,
+			Primitive: &ast.Value_Raw{
+				Raw: raw,
+			},
+		}
+	}
+	err = acpArgs.VerifyTopUp(capacity.GetU(), udtAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Value{
+		T: ast.Value_CELL,
+		Children: []*ast.Value{
+			&ast.Value{
+				T: ast.Value_UINT64,
+				Primitive: &ast.Value_U{
+					U: uint64(output.Capacity) + capacity.GetU(),
+				},
+			},
+			cell.GetChildren()[1],
+			cell.GetChildren()[2],
+			newData,
+		},
+	}, nil
+}
+
+// evaluateTypeID accepts either the TRANSACTION creating the type id cell,
+// or its first input directly, which is handy when the transaction outputs
+// depend on the type id.
+func evaluateTypeID(value *ast.Value, outputIndex *ast.Value) (*ast.Value, error) {
+	if outputIndex.GetT() != ast.Value_UINT64 {
+		return nil, fmt.Errorf("Invalid operand type to TYPE_ID")
+	}
+	input := value
+	switch value.GetT() {
+	case ast.Value_TRANSACTION:
+		inputs := value.GetChildren()[0].GetChildren()
+		if len(inputs) == 0 {
+			return nil, fmt.Errorf("Transaction has no inputs to derive type id!")
+		}
+		input = inputs[0]
+	case ast.Value_CELL:
+		var err error
+		input, err = cellToCellInput(value, &ast.Value{
+			T: ast.Value_UINT64,
+			Primitive: &ast.Value_U{
+				U: 0,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	case ast.Value_CELL_INPUT:
+	default:
+		return nil, fmt.Errorf("Invalid operand type to TYPE_ID")
+	}
+	cellInput, err := ast.RestoreCellInput(input, true)
+	if err != nil {
+		return nil, err
+	}
+	typeID, err := rpctypes.CalculateTypeID(cellInput, outputIndex.GetU())
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Value{
+		T: ast.Value_BYTES,
+		Primitive: &ast.Value_Raw{
+			Raw: typeID,
+		},
+	}, nil
+}
+
+func uint64List(values ...uint64) *ast.Value {
+	children := make([]*ast.Value, len(values))
+	for i, value := range values {
+		children[i] = &ast.Value{
+			T: ast.Value_UINT64,
+			Primitive: &ast.Value_U{
+				U: value,
+			},
+		}
+	}
+	return &ast.Value{
+		T:        ast.Value_LIST,
+		Children: children,
+	}
+}
+
 func valueToBigInt(value *ast.Value) (*big.Int, error) {
 	i := new(big.Int)
 	if value.GetT() == ast.Value_BYTES {
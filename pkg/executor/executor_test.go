@@ -1,10 +1,19 @@
 package executor
 
 import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/xxuejie/animagus/pkg/address"
 	"github.com/xxuejie/animagus/pkg/ast"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
 )
 
 type testEnvironment struct {
@@ -128,3 +137,681 @@ func TestTailRecursion(t *testing.T) {
 		t.Errorf("Invalid result: %d, expected: 89", value.GetU())
 	}
 }
+
+func bytes_value(b []byte) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_BYTES,
+		Primitive: &ast.Value_Raw{
+			Raw: b,
+		},
+	}
+}
+
+func TestAddressRoundTrip(t *testing.T) {
+	a := "ckb1qzda0cr08m85hc8jlnfp3zer7xulejywt49kt2rr0vthywaa50xwsqdnnw7qkdnnclfkg59uzn8umtfd2kwxceqxwquc4"
+	script := &ast.Value{
+		T: ast.Value_DECODE_ADDRESS,
+		Children: []*ast.Value{
+			bytes_value([]byte("ckb1qyqt8xaupvm8837nv3gtc9x0ekkj64vud3jqfwyw5v")),
+			bytes_value([]byte("ckb")),
+		},
+	}
+	f := &ast.Value{
+		T: ast.Value_ENCODE_ADDRESS,
+		Children: []*ast.Value{
+			script,
+			bytes_value([]byte("ckb")),
+		},
+	}
+
+	value, err := Execute(f, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value.GetRaw()) != a {
+		t.Errorf("Invalid address: %s, expected: %s", value.GetRaw(), a)
+	}
+
+	script.Children[1] = bytes_value([]byte("ckt"))
+	_, err = Execute(f, &testEnvironment{})
+	if err == nil {
+		t.Errorf("Address with mismatched prefix is accepted!")
+	}
+}
+
+func TestHeaderHash(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("..", "rpctypes", "testdata", "block1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block rpctypes.Block
+	err = json.Unmarshal(data, &block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &ast.Value{
+		T:        ast.Value_HASH,
+		Children: []*ast.Value{ast.ConvertHeader(block.Header)},
+	}
+	value, err := Execute(f, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "7da7da17aeb1bec53c2f42364d59534435e741d7ac9cc1dcb694c2c3e37c4e3e"
+	if hex.EncodeToString(value.GetRaw()) != expected {
+		t.Errorf("Invalid header hash: %x, expected: %s", value.GetRaw(), expected)
+	}
+}
+
+func TestDecodeEpoch(t *testing.T) {
+	f := &ast.Value{
+		T:        ast.Value_DECODE_EPOCH,
+		Children: []*ast.Value{uint_value(0x7080291000032)},
+	}
+	value, err := Execute(f, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint64{50, 657, 1800}
+	for i, child := range value.GetChildren() {
+		if child.GetU() != expected[i] {
+			t.Errorf("Invalid epoch field %d: %d, expected: %d", i, child.GetU(), expected[i])
+		}
+	}
+}
+
+func testCell(capacity uint64, txHash byte) *ast.Value {
+	lock := rpctypes.Script{
+		HashType: rpctypes.Type,
+		Args:     make([]byte, 20),
+	}
+	outPoint := rpctypes.OutPoint{
+		Index: 0,
+	}
+	outPoint.TxHash[0] = txHash
+	return ast.ConvertCell(rpctypes.CellOutput{
+		Capacity: rpctypes.Uint64(capacity),
+		Lock:     lock,
+	}, []byte{}, outPoint, nil)
+}
+
+func TestTransactionSinceInput(t *testing.T) {
+	cell := testCell(1000, 1)
+	since := &ast.Value{
+		T: ast.Value_ENCODE_SINCE,
+		Children: []*ast.Value{
+			uint_value(rpctypes.SinceMetricBlockNumber),
+			&ast.Value{
+				T:         ast.Value_BOOL,
+				Primitive: &ast.Value_B{B: true},
+			},
+			uint_value(100),
+		},
+	}
+	f := &ast.Value{
+		T: ast.Value_SERIALIZE_TO_JSON,
+		Children: []*ast.Value{
+			&ast.Value{
+				T: ast.Value_TRANSACTION,
+				Children: []*ast.Value{
+					&ast.Value{
+						T: ast.Value_LIST,
+						Children: []*ast.Value{
+							&ast.Value{
+								T:        ast.Value_CELL_INPUT,
+								Children: []*ast.Value{cell, since},
+							},
+						},
+					},
+					&ast.Value{
+						T:        ast.Value_LIST,
+						Children: []*ast.Value{testCell(900, 0)},
+					},
+					&ast.Value{T: ast.Value_LIST},
+				},
+			},
+		},
+	}
+	value, err := Execute(f, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx rpctypes.Transaction
+	err = json.Unmarshal(value.GetRaw(), &tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Inputs) != 1 || tx.Inputs[0].Since != 0x8000000000000064 {
+		t.Errorf("Invalid transaction inputs: %+v", tx.Inputs)
+	}
+}
+
+func TestTransactionWithoutWitnesses(t *testing.T) {
+	headerDep := make([]byte, 32)
+	headerDep[0] = 1
+	tx := &ast.Value{
+		T:         ast.Value_TRANSACTION,
+		Primitive: &ast.Value_U{U: ast.CheckConsensus},
+		Children: []*ast.Value{
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(1000*rpctypes.ShannonsPerByte, 1)},
+			},
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(900*rpctypes.ShannonsPerByte, 0)},
+			},
+			&ast.Value{T: ast.Value_LIST},
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{bytes_value(headerDep)},
+			},
+		},
+	}
+	f := &ast.Value{
+		T:        ast.Value_SERIALIZE_TO_JSON,
+		Children: []*ast.Value{tx},
+	}
+	value, err := Execute(f, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result rpctypes.Transaction
+	err = json.Unmarshal(value.GetRaw(), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.HeaderDeps) != 1 || !bytes.Equal(result.HeaderDeps[0][:], headerDep) {
+		t.Errorf("Invalid header deps: %+v", result.HeaderDeps)
+	}
+	if len(result.Witnesses) != 1 || len(result.Witnesses[0]) != 0 {
+		t.Errorf("Invalid witnesses: %+v", result.Witnesses)
+	}
+
+	evaluated, err := Execute(tx, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ast.RestoreTransaction(&ast.Value{
+		T:        ast.Value_TRANSACTION,
+		Children: evaluated.GetChildren()[:4],
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.HeaderDeps) != 1 || len(restored.Witnesses) != 1 {
+		t.Errorf("Invalid restored transaction: %+v", restored)
+	}
+}
+
+func TestOccupiedCapacityCheck(t *testing.T) {
+	output := testCell(1000, 0)
+	f := &ast.Value{
+		T:        ast.Value_OCCUPIED_CAPACITY,
+		Children: []*ast.Value{output},
+	}
+	value, err := Execute(f, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.GetU() != 61*rpctypes.ShannonsPerByte {
+		t.Errorf("Invalid occupied capacity: %d", value.GetU())
+	}
+
+	tx := &ast.Value{
+		T: ast.Value_TRANSACTION,
+		Children: []*ast.Value{
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(1000, 1)},
+			},
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{output},
+			},
+			&ast.Value{T: ast.Value_LIST},
+		},
+	}
+	_, err = Execute(tx, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Primitive = &ast.Value_U{U: ast.CheckOccupiedCapacity}
+	_, err = Execute(tx, &testEnvironment{})
+	if err == nil {
+		t.Errorf("Output below occupied capacity is accepted!")
+	}
+}
+
+func TestConsensusCheck(t *testing.T) {
+	capacity := 100 * rpctypes.ShannonsPerByte
+	transaction := func(inputs []*ast.Value, outputs []*ast.Value) *ast.Value {
+		return &ast.Value{
+			T: ast.Value_SERIALIZE_TO_JSON,
+			Children: []*ast.Value{
+				&ast.Value{
+					T:         ast.Value_TRANSACTION,
+					Primitive: &ast.Value_U{U: ast.CheckConsensus},
+					Children: []*ast.Value{
+						&ast.Value{
+							T:        ast.Value_LIST,
+							Children: inputs,
+						},
+						&ast.Value{
+							T:        ast.Value_LIST,
+							Children: outputs,
+						},
+						&ast.Value{T: ast.Value_LIST},
+					},
+				},
+			},
+		}
+	}
+
+	_, err := Execute(transaction(
+		[]*ast.Value{testCell(capacity, 1), testCell(capacity, 2)},
+		[]*ast.Value{testCell(2*capacity, 0)}), &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := map[string]*ast.Value{
+		"no inputs": transaction(
+			[]*ast.Value{},
+			[]*ast.Value{testCell(capacity, 0)}),
+		"no outputs": transaction(
+			[]*ast.Value{testCell(capacity, 1)},
+			[]*ast.Value{}),
+		"duplicate inputs": transaction(
+			[]*ast.Value{testCell(capacity, 1), testCell(capacity, 1)},
+			[]*ast.Value{testCell(capacity, 0)}),
+		"insufficient capacity": transaction(
+			[]*ast.Value{testCell(capacity, 1)},
+			[]*ast.Value{testCell(2*capacity, 0)}),
+		"occupied capacity": transaction(
+			[]*ast.Value{testCell(capacity, 1)},
+			[]*ast.Value{testCell(1000, 0)}),
+	}
+	for name, f := range invalid {
+		_, err := Execute(f, &testEnvironment{})
+		if err == nil {
+			t.Errorf("Transaction with %s is accepted!", name)
+		}
+	}
+
+	// Cells are validated when evaluated, check that values passed to
+	// CheckTransaction directly are validated as well.
+	tx, err := Execute(transaction(
+		[]*ast.Value{testCell(capacity, 1)},
+		[]*ast.Value{testCell(capacity, 0)}).GetChildren()[0], &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := testCell(capacity, 0)
+	output.Children = output.GetChildren()[:3]
+	tx.GetChildren()[1].Children = []*ast.Value{output}
+	err = ast.CheckTransaction(tx, ast.CheckConsensus)
+	if err == nil || !strings.Contains(err.Error(), "Output 0") {
+		t.Errorf("Output without data is accepted: %v", err)
+	}
+}
+
+func TestDeductFee(t *testing.T) {
+	capacity := 100 * rpctypes.ShannonsPerByte
+	tx := &ast.Value{
+		T: ast.Value_TRANSACTION,
+		Children: []*ast.Value{
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(capacity, 1), testCell(capacity, 2)},
+			},
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(capacity, 0), testCell(capacity, 0)},
+			},
+			&ast.Value{T: ast.Value_LIST},
+		},
+	}
+	f := &ast.Value{
+		T:        ast.Value_DEDUCT_FEE,
+		Children: []*ast.Value{tx, uint_value(1000), uint_value(1)},
+	}
+	value, err := Execute(f, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ast.RestoreTransaction(value, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both inputs share the same lock, only the first one needs a signature.
+	restored.Witnesses[0], err = rpctypes.SigningWitness(nil, rpctypes.Secp256k1SignatureSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := restored.SerializedSizeInBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(restored.Outputs[0].Capacity) != capacity ||
+		uint64(restored.Outputs[1].Capacity) != capacity-size {
+		t.Errorf("Invalid fee deducted, size: %d, outputs: %+v", size, restored.Outputs)
+	}
+
+	f.Children[1] = uint_value(1000 * 1000 * rpctypes.ShannonsPerByte)
+	_, err = Execute(f, &testEnvironment{})
+	if err == nil {
+		t.Errorf("Change output below occupied capacity is accepted!")
+	}
+}
+
+func TestSigningEntries(t *testing.T) {
+	capacity := 100 * rpctypes.ShannonsPerByte
+	other := testCell(capacity, 3)
+	other.Children[1] = ast.ConvertScript(rpctypes.Script{
+		HashType: rpctypes.Type,
+		Args:     make([]byte, 21),
+	})
+	tx := &ast.Value{
+		T: ast.Value_TRANSACTION,
+		Children: []*ast.Value{
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(capacity, 1), other, testCell(capacity, 2)},
+			},
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(3*capacity, 0)},
+			},
+			&ast.Value{T: ast.Value_LIST},
+		},
+	}
+	f := &ast.Value{
+		T:        ast.Value_SIGNING_ENTRIES,
+		Children: []*ast.Value{tx},
+	}
+	value, err := Execute(f, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluatedTx, err := Execute(tx, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ast.RestoreTransaction(evaluatedTx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := [][]int{{0, 2}, {1}}
+	if len(value.GetChildren()) != len(groups) {
+		t.Fatalf("Invalid number of signing entries: %d", len(value.GetChildren()))
+	}
+	for i, group := range groups {
+		entry := value.GetChildren()[i]
+		indices := entry.GetChildren()[1].GetChildren()
+		if len(indices) != len(group) {
+			t.Fatalf("Invalid indices in entry %d: %v", i, indices)
+		}
+		for j, index := range group {
+			if indices[j].GetU() != uint64(index) {
+				t.Errorf("Invalid indices in entry %d: %v", i, indices)
+			}
+		}
+		message, err := restored.SigningMessage(group, rpctypes.Secp256k1SignatureSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(entry.GetChildren()[2].GetRaw(), message) {
+			t.Errorf("Invalid signing message in entry %d", i)
+		}
+	}
+}
+
+func TestSigningRequest(t *testing.T) {
+	capacity := 100 * rpctypes.ShannonsPerByte
+	tx := &ast.Value{
+		T: ast.Value_TRANSACTION,
+		Children: []*ast.Value{
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(capacity, 1)},
+			},
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(capacity, 0)},
+			},
+			&ast.Value{T: ast.Value_LIST},
+		},
+	}
+	value, err := Execute(&ast.Value{
+		T:        ast.Value_SIGNING_REQUEST,
+		Children: []*ast.Value{tx},
+	}, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialized, err := Execute(&ast.Value{
+		T:        ast.Value_SERIALIZE_TO_JSON,
+		Children: []*ast.Value{tx},
+	}, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Execute(&ast.Value{
+		T:        ast.Value_SIGNING_ENTRIES,
+		Children: []*ast.Value{tx},
+	}, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(value.GetChildren()) != 2 {
+		t.Fatalf("Invalid signing request: %v", value)
+	}
+	if !proto.Equal(value.GetChildren()[0], serialized) {
+		t.Errorf("Invalid serialized transaction: %v", value.GetChildren()[0])
+	}
+	if !proto.Equal(value.GetChildren()[1], entries) {
+		t.Errorf("Invalid signing entries: %v", value.GetChildren()[1])
+	}
+}
+
+func TestMultisigSigningEntries(t *testing.T) {
+	capacity := 200 * rpctypes.ShannonsPerByte
+	hashes := &ast.Value{
+		T: ast.Value_LIST,
+		Children: []*ast.Value{
+			bytes_value(bytes.Repeat([]byte{1}, 20)),
+			bytes_value(bytes.Repeat([]byte{2}, 20)),
+		},
+	}
+	args, err := Execute(&ast.Value{
+		T:        ast.Value_MULTISIG_LOCK_ARGS,
+		Children: []*ast.Value{hashes, uint_value(2), uint_value(0)},
+	}, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := testCell(capacity, 1)
+	input.Children[1] = ast.ConvertScript(rpctypes.Script{
+		CodeHash: address.Secp256k1MultisigTypeHash,
+		HashType: rpctypes.Type,
+		Args:     args.GetRaw(),
+	})
+	witnesses := &ast.Value{T: ast.Value_LIST}
+	tx := &ast.Value{
+		T: ast.Value_TRANSACTION,
+		Children: []*ast.Value{
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{input},
+			},
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{testCell(capacity, 0)},
+			},
+			&ast.Value{T: ast.Value_LIST},
+			&ast.Value{T: ast.Value_LIST},
+			witnesses,
+		},
+	}
+	fee := &ast.Value{
+		T:        ast.Value_FEE,
+		Children: []*ast.Value{tx, uint_value(1000)},
+	}
+	_, err = Execute(fee, &testEnvironment{})
+	if err == nil {
+		t.Errorf("Multisig input without placeholder witness is accepted!")
+	}
+
+	witnesses.Children = []*ast.Value{
+		&ast.Value{
+			T:        ast.Value_MULTISIG_WITNESS,
+			Children: []*ast.Value{hashes, uint_value(2), uint_value(0)},
+		},
+	}
+	_, err = Execute(fee, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := Execute(&ast.Value{
+		T:        ast.Value_SIGNING_ENTRIES,
+		Children: []*ast.Value{tx},
+	}, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluatedTx, err := Execute(tx, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ast.RestoreTransaction(evaluatedTx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.Witnesses[0]) != 16+4+4+20*2+65*2 {
+		t.Errorf("Invalid multisig witness length: %d", len(restored.Witnesses[0]))
+	}
+	message, err := restored.SigningMessageWithWitness([]int{0}, restored.Witnesses[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(value.GetChildren()) != 1 ||
+		!bytes.Equal(value.GetChildren()[0].GetChildren()[2].GetRaw(), message) {
+		t.Errorf("Invalid multisig signing entries!")
+	}
+}
+
+func TestAcpTopUp(t *testing.T) {
+	lock := &ast.Value{
+		T: ast.Value_ACP_LOCK,
+		Children: []*ast.Value{
+			bytes_value([]byte(address.Testnet)),
+			bytes_value(bytes.Repeat([]byte{1}, 20)),
+			uint_value(9),
+			uint_value(2),
+		},
+	}
+	isAcp, err := Execute(&ast.Value{
+		T:        ast.Value_IS_ACP_LOCK,
+		Children: []*ast.Value{lock},
+	}, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isAcp.GetB() {
+		t.Errorf("Anyone-can-pay lock is not recognized!")
+	}
+
+	data := make([]byte, 16)
+	data[0] = 0xFF
+	cell := &ast.Value{
+		T: ast.Value_CELL,
+		Children: []*ast.Value{
+			uint_value(200 * rpctypes.ShannonsPerByte),
+			lock,
+			ast.ConvertScript(rpctypes.Script{Args: []byte{2}}),
+			bytes_value(data),
+		},
+	}
+	topUp := &ast.Value{
+		T:        ast.Value_ACP_TOP_UP,
+		Children: []*ast.Value{cell, uint_value(0), uint_value(100)},
+	}
+	value, err := Execute(topUp, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.GetChildren()[0].GetU() != 200*rpctypes.ShannonsPerByte ||
+		hex.EncodeToString(value.GetChildren()[3].GetRaw()) != "63010000000000000000000000000000" {
+		t.Errorf("Invalid topped up cell: %v", value)
+	}
+
+	topUp.Children[2] = uint_value(99)
+	_, err = Execute(topUp, &testEnvironment{})
+	if err == nil {
+		t.Errorf("Payment below minimum is accepted!")
+	}
+}
+
+func TestTypeID(t *testing.T) {
+	capacity := 1000 * rpctypes.ShannonsPerByte
+	input := testCell(capacity, 1)
+	typeScript := &ast.Value{
+		T: ast.Value_SCRIPT,
+		Children: []*ast.Value{
+			bytes_value(rpctypes.TypeIDCodeHash[:]),
+			uint_value(uint64(rpctypes.Type)),
+			&ast.Value{
+				T:        ast.Value_TYPE_ID,
+				Children: []*ast.Value{input, uint_value(0)},
+			},
+		},
+	}
+	tx := &ast.Value{
+		T: ast.Value_TRANSACTION,
+		Children: []*ast.Value{
+			&ast.Value{
+				T:        ast.Value_LIST,
+				Children: []*ast.Value{input},
+			},
+			&ast.Value{
+				T: ast.Value_LIST,
+				Children: []*ast.Value{
+					&ast.Value{
+						T: ast.Value_CELL,
+						Children: []*ast.Value{
+							uint_value(capacity),
+							input.GetChildren()[1],
+							typeScript,
+							bytes_value([]byte{}),
+						},
+					},
+				},
+			},
+			&ast.Value{T: ast.Value_LIST},
+		},
+	}
+	value, err := Execute(tx, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeID, err := Execute(&ast.Value{
+		T:        ast.Value_TYPE_ID,
+		Children: []*ast.Value{value, uint_value(0)},
+	}, &testEnvironment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ast.RestoreTransaction(value, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := rpctypes.CalculateTypeID(restored.Inputs[0], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(typeID.GetRaw(), expected) ||
+		!bytes.Equal(restored.Outputs[0].Type.Args, expected) {
+		t.Errorf("Invalid type id: %x", typeID.GetRaw())
+	}
+}
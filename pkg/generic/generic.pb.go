@@ -72,27 +72,201 @@ func (m *GenericParams) GetParams() []*ast.Value {
 	return nil
 }
 
+type StatusParams struct {
+	// Program name, it can be omitted when only one program is loaded.
+	Program              string   `protobuf:"bytes,1,opt,name=program,proto3" json:"program,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatusParams) Reset()         { *m = StatusParams{} }
+func (m *StatusParams) String() string { return proto.CompactTextString(m) }
+func (*StatusParams) ProtoMessage()    {}
+func (*StatusParams) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4c692b03a02b431c, []int{1}
+}
+
+func (m *StatusParams) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatusParams.Unmarshal(m, b)
+}
+func (m *StatusParams) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatusParams.Marshal(b, m, deterministic)
+}
+func (m *StatusParams) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatusParams.Merge(m, src)
+}
+func (m *StatusParams) XXX_Size() int {
+	return xxx_messageInfo_StatusParams.Size(m)
+}
+func (m *StatusParams) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatusParams.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatusParams proto.InternalMessageInfo
+
+func (m *StatusParams) GetProgram() string {
+	if m != nil {
+		return m.Program
+	}
+	return ""
+}
+
+type Status struct {
+	Program string `protobuf:"bytes,1,opt,name=program,proto3" json:"program,omitempty"`
+	AstHash []byte `protobuf:"bytes,2,opt,name=ast_hash,json=astHash,proto3" json:"ast_hash,omitempty"`
+	// Last indexed block, indexed_block_hash is empty when no block is indexed
+	// yet.
+	IndexedBlockNumber uint64 `protobuf:"varint,3,opt,name=indexed_block_number,json=indexedBlockNumber,proto3" json:"indexed_block_number,omitempty"`
+	IndexedBlockHash   []byte `protobuf:"bytes,4,opt,name=indexed_block_hash,json=indexedBlockHash,proto3" json:"indexed_block_hash,omitempty"`
+	// Chain tip from the block source, tip_known is false when the source does
+	// not know the chain tip, tip_block_number and lag are 0 then.
+	TipBlockNumber uint64 `protobuf:"varint,5,opt,name=tip_block_number,json=tipBlockNumber,proto3" json:"tip_block_number,omitempty"`
+	Lag            uint64 `protobuf:"varint,6,opt,name=lag,proto3" json:"lag,omitempty"`
+	// Averaged over the last minute.
+	BlocksPerSecond float64 `protobuf:"fixed64,7,opt,name=blocks_per_second,json=blocksPerSecond,proto3" json:"blocks_per_second,omitempty"`
+	// Block reverted by the last reorg and unix time in seconds of the reorg,
+	// last_reorg_time is 0 when no reorg happens since animagus starts.
+	LastReorgBlockNumber uint64   `protobuf:"varint,8,opt,name=last_reorg_block_number,json=lastReorgBlockNumber,proto3" json:"last_reorg_block_number,omitempty"`
+	LastReorgTime        int64    `protobuf:"varint,9,opt,name=last_reorg_time,json=lastReorgTime,proto3" json:"last_reorg_time,omitempty"`
+	TipKnown             bool     `protobuf:"varint,10,opt,name=tip_known,json=tipKnown,proto3" json:"tip_known,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Status) Reset()         { *m = Status{} }
+func (m *Status) String() string { return proto.CompactTextString(m) }
+func (*Status) ProtoMessage()    {}
+func (*Status) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4c692b03a02b431c, []int{2}
+}
+
+func (m *Status) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Status.Unmarshal(m, b)
+}
+func (m *Status) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Status.Marshal(b, m, deterministic)
+}
+func (m *Status) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Status.Merge(m, src)
+}
+func (m *Status) XXX_Size() int {
+	return xxx_messageInfo_Status.Size(m)
+}
+func (m *Status) XXX_DiscardUnknown() {
+	xxx_messageInfo_Status.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Status proto.InternalMessageInfo
+
+func (m *Status) GetProgram() string {
+	if m != nil {
+		return m.Program
+	}
+	return ""
+}
+
+func (m *Status) GetAstHash() []byte {
+	if m != nil {
+		return m.AstHash
+	}
+	return nil
+}
+
+func (m *Status) GetIndexedBlockNumber() uint64 {
+	if m != nil {
+		return m.IndexedBlockNumber
+	}
+	return 0
+}
+
+func (m *Status) GetIndexedBlockHash() []byte {
+	if m != nil {
+		return m.IndexedBlockHash
+	}
+	return nil
+}
+
+func (m *Status) GetTipBlockNumber() uint64 {
+	if m != nil {
+		return m.TipBlockNumber
+	}
+	return 0
+}
+
+func (m *Status) GetLag() uint64 {
+	if m != nil {
+		return m.Lag
+	}
+	return 0
+}
+
+func (m *Status) GetBlocksPerSecond() float64 {
+	if m != nil {
+		return m.BlocksPerSecond
+	}
+	return 0
+}
+
+func (m *Status) GetLastReorgBlockNumber() uint64 {
+	if m != nil {
+		return m.LastReorgBlockNumber
+	}
+	return 0
+}
+
+func (m *Status) GetLastReorgTime() int64 {
+	if m != nil {
+		return m.LastReorgTime
+	}
+	return 0
+}
+
+func (m *Status) GetTipKnown() bool {
+	if m != nil {
+		return m.TipKnown
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*GenericParams)(nil), "generic.GenericParams")
+	proto.RegisterType((*StatusParams)(nil), "generic.StatusParams")
+	proto.RegisterType((*Status)(nil), "generic.Status")
 }
 
 func init() { proto.RegisterFile("generic.proto", fileDescriptor_4c692b03a02b431c) }
 
 var fileDescriptor_4c692b03a02b431c = []byte{
-	// 197 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4d, 0x4f, 0xcd, 0x4b,
-	0x2d, 0xca, 0x4c, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x87, 0x72, 0xa5, 0x38, 0x13,
-	0x8b, 0x4b, 0x20, 0x62, 0x4a, 0xee, 0x5c, 0xbc, 0xee, 0x10, 0xd1, 0x80, 0xc4, 0xa2, 0xc4, 0xdc,
-	0x62, 0x21, 0x21, 0x2e, 0x96, 0xbc, 0xc4, 0xdc, 0x54, 0x09, 0x46, 0x05, 0x46, 0x0d, 0xce, 0x20,
-	0x30, 0x5b, 0x48, 0x89, 0x8b, 0xad, 0x00, 0x2c, 0x2b, 0xc1, 0xa4, 0xc0, 0xac, 0xc1, 0x6d, 0xc4,
-	0xa5, 0x07, 0x32, 0x20, 0x2c, 0x31, 0xa7, 0x34, 0x35, 0x08, 0x2a, 0x63, 0x54, 0xc0, 0xc5, 0x07,
-	0x35, 0x28, 0x38, 0xb5, 0xa8, 0x2c, 0x33, 0x39, 0x55, 0x48, 0x87, 0x8b, 0xc5, 0x39, 0x31, 0x27,
-	0x47, 0x48, 0x4c, 0x0f, 0xe6, 0x0c, 0x14, 0x9b, 0xa4, 0x90, 0x4c, 0x51, 0x62, 0x10, 0x32, 0xe0,
-	0x62, 0x0b, 0x2e, 0x29, 0x4a, 0x4d, 0xcc, 0x25, 0x4e, 0xbd, 0x01, 0xa3, 0x93, 0x66, 0x94, 0x7a,
-	0x7a, 0x66, 0x49, 0x46, 0x69, 0x92, 0x5e, 0x72, 0x7e, 0xae, 0x7e, 0x45, 0x45, 0x69, 0x6a, 0x56,
-	0x66, 0xaa, 0x7e, 0x62, 0x5e, 0x66, 0x6e, 0x62, 0x7a, 0x69, 0xb1, 0x7e, 0x41, 0x76, 0xba, 0x3e,
-	0xd4, 0xa0, 0x24, 0x36, 0xb0, 0x67, 0x8d, 0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0x34, 0x56, 0xfa,
-	0x35, 0x11, 0x01, 0x00, 0x00,
+	// 428 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xcf, 0x8e, 0xd3, 0x30,
+	0x10, 0xc6, 0xd7, 0x9b, 0xd2, 0x3f, 0xc3, 0x76, 0x5b, 0xac, 0x05, 0x4c, 0xb9, 0x44, 0x39, 0x80,
+	0x41, 0xab, 0xb6, 0x2a, 0xe2, 0x05, 0x96, 0xc3, 0x22, 0x21, 0xa1, 0x55, 0x8a, 0x38, 0x70, 0x89,
+	0xdc, 0x74, 0x94, 0x9a, 0xc6, 0x49, 0x64, 0x3b, 0xd0, 0x2b, 0xef, 0xc2, 0x83, 0x22, 0xbb, 0x69,
+	0x95, 0x20, 0x21, 0x71, 0xf3, 0xcc, 0xf7, 0x7d, 0x3f, 0x6b, 0x34, 0x03, 0xe3, 0x0c, 0x0b, 0xd4,
+	0x32, 0x9d, 0x57, 0xba, 0xb4, 0x25, 0x1d, 0x34, 0xe5, 0x6c, 0x24, 0x8c, 0x3d, 0xf6, 0xa2, 0x7b,
+	0x18, 0xdf, 0x1f, 0xbb, 0x0f, 0x42, 0x0b, 0x65, 0x28, 0x85, 0x5e, 0x21, 0x14, 0x32, 0x12, 0x12,
+	0x3e, 0x8a, 0xfd, 0x9b, 0x46, 0xd0, 0xaf, 0xbc, 0xca, 0x2e, 0xc3, 0x80, 0x3f, 0x5e, 0xc1, 0xdc,
+	0x01, 0xbe, 0x8a, 0xbc, 0xc6, 0xb8, 0x51, 0x22, 0x0e, 0x57, 0x6b, 0x2b, 0x6c, 0x6d, 0x1a, 0x0e,
+	0x83, 0x41, 0xa5, 0xcb, 0x4c, 0x0b, 0xd5, 0xa0, 0x4e, 0x65, 0xf4, 0x2b, 0x80, 0xfe, 0xd1, 0xfa,
+	0x6f, 0x13, 0x7d, 0x01, 0x43, 0x61, 0x6c, 0xb2, 0x13, 0x66, 0xc7, 0x2e, 0x43, 0xc2, 0xaf, 0xe2,
+	0x81, 0x30, 0xf6, 0xa3, 0x30, 0x3b, 0xba, 0x84, 0x1b, 0x59, 0x6c, 0xf1, 0x80, 0xdb, 0x64, 0x93,
+	0x97, 0xe9, 0x3e, 0x29, 0x6a, 0xb5, 0x41, 0xcd, 0x82, 0x90, 0xf0, 0x5e, 0x4c, 0x1b, 0xed, 0xce,
+	0x49, 0x9f, 0xbd, 0x42, 0x6f, 0x81, 0x76, 0x13, 0x1e, 0xdb, 0xf3, 0xd8, 0x69, 0xdb, 0xef, 0xf9,
+	0x1c, 0xa6, 0x56, 0x56, 0x5d, 0xf6, 0x23, 0xcf, 0xbe, 0xb6, 0xb2, 0x6a, 0x73, 0xa7, 0x10, 0xe4,
+	0x22, 0x63, 0x7d, 0x2f, 0xba, 0x27, 0x7d, 0x0b, 0x4f, 0x7c, 0xce, 0x24, 0x15, 0xea, 0xc4, 0x60,
+	0x5a, 0x16, 0x5b, 0x36, 0x08, 0x09, 0x27, 0xf1, 0xe4, 0x28, 0x3c, 0xa0, 0x5e, 0xfb, 0x36, 0x7d,
+	0x0f, 0xcf, 0x73, 0x37, 0xa3, 0xc6, 0x52, 0x67, 0xdd, 0xef, 0x86, 0x9e, 0x78, 0xe3, 0xe4, 0xd8,
+	0xa9, 0xed, 0x4f, 0x5f, 0xc1, 0xa4, 0x15, 0xb3, 0x52, 0x21, 0x1b, 0x85, 0x84, 0x07, 0xf1, 0xf8,
+	0x6c, 0xff, 0x22, 0x15, 0xd2, 0x97, 0x30, 0x72, 0x63, 0xec, 0x8b, 0xf2, 0x67, 0xc1, 0x20, 0x24,
+	0x7c, 0x18, 0x0f, 0xad, 0xac, 0x3e, 0xb9, 0x7a, 0xf5, 0x9b, 0xc0, 0x75, 0xb3, 0xf7, 0x35, 0xea,
+	0x1f, 0x32, 0x45, 0x7a, 0x0b, 0xbd, 0x0f, 0x22, 0xcf, 0xe9, 0xb3, 0xf9, 0xe9, 0x6a, 0x3a, 0x87,
+	0x31, 0x6b, 0x2d, 0x3d, 0xba, 0xa0, 0x4b, 0xb7, 0x43, 0x8d, 0x42, 0xfd, 0x9f, 0x7f, 0x49, 0xe8,
+	0xea, 0xbc, 0xf5, 0xa7, 0xe7, 0x44, 0xfb, 0x62, 0x66, 0x93, 0xbf, 0xda, 0xd1, 0xc5, 0xdd, 0x9b,
+	0x6f, 0xaf, 0x33, 0x69, 0x77, 0xf5, 0x66, 0x9e, 0x96, 0x6a, 0x71, 0x38, 0xd4, 0xf8, 0x5d, 0xe2,
+	0x42, 0x14, 0x52, 0x89, 0xac, 0x36, 0x8b, 0x6a, 0x9f, 0x2d, 0x9a, 0xcc, 0xa6, 0xef, 0xef, 0xf9,
+	0xdd, 0x9f, 0x01, 0x00, 0x7c, 0x0c, 0xce, 0xad, 0xf4, 0x02, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -109,6 +283,7 @@ const _ = grpc.SupportPackageIsVersion4
 type GenericServiceClient interface {
 	Call(ctx context.Context, in *GenericParams, opts ...grpc.CallOption) (*ast.Value, error)
 	Stream(ctx context.Context, in *GenericParams, opts ...grpc.CallOption) (GenericService_StreamClient, error)
+	Status(ctx context.Context, in *StatusParams, opts ...grpc.CallOption) (*Status, error)
 }
 
 type genericServiceClient struct {
@@ -160,10 +335,20 @@ func (x *genericServiceStreamClient) Recv() (*ast.Value, error) {
 	return m, nil
 }
 
+func (c *genericServiceClient) Status(ctx context.Context, in *StatusParams, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/generic.GenericService/Status", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GenericServiceServer is the server API for GenericService service.
 type GenericServiceServer interface {
 	Call(context.Context, *GenericParams) (*ast.Value, error)
 	Stream(*GenericParams, GenericService_StreamServer) error
+	Status(context.Context, *StatusParams) (*Status, error)
 }
 
 // UnimplementedGenericServiceServer can be embedded to have forward compatible implementations.
@@ -176,6 +361,9 @@ func (*UnimplementedGenericServiceServer) Call(ctx context.Context, req *Generic
 func (*UnimplementedGenericServiceServer) Stream(req *GenericParams, srv GenericService_StreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
 }
+func (*UnimplementedGenericServiceServer) Status(ctx context.Context, req *StatusParams) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
 
 func RegisterGenericServiceServer(s *grpc.Server, srv GenericServiceServer) {
 	s.RegisterService(&_GenericService_serviceDesc, srv)
@@ -220,6 +408,24 @@ func (x *genericServiceStreamServer) Send(m *ast.Value) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _GenericService_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GenericServiceServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/generic.GenericService/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GenericServiceServer).Status(ctx, req.(*StatusParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _GenericService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "generic.GenericService",
 	HandlerType: (*GenericServiceServer)(nil),
@@ -228,6 +434,10 @@ var _GenericService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Call",
 			Handler:    _GenericService_Call_Handler,
 		},
+		{
+			MethodName: "Status",
+			Handler:    _GenericService_Status_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
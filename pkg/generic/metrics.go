@@ -0,0 +1,44 @@
+package generic
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Calls that cannot be resolved are counted as errors with empty labels, so
+// clients cannot create arbitrary label values.
+var (
+	callDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "animagus",
+		Subsystem: "server",
+		Name:      "call_duration_seconds",
+		Help:      "Time to execute a call.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"program", "call"})
+	callErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "animagus",
+		Subsystem: "server",
+		Name:      "call_errors_total",
+		Help:      "Number of calls returning an error.",
+	}, []string{"program", "call"})
+	queryCells = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "animagus",
+		Subsystem: "server",
+		Name:      "query_cells",
+		Help:      "Number of cells returned by a QUERY_CELLS.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+	}, []string{"program", "call"})
+	getCellsDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "animagus",
+		Subsystem: "server",
+		Name:      "get_cells_duration_seconds",
+		Help:      "Time to resolve cells from the block source, which is a getCells query for the GraphQL source.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"program"})
+	streamSubscribers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "animagus",
+		Subsystem: "server",
+		Name:      "stream_subscribers",
+		Help:      "Number of active stream subscribers.",
+	}, []string{"program", "stream"})
+)
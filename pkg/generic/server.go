@@ -2,18 +2,21 @@ package generic
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
-	"github.com/gomodule/redigo/redis"
-	"github.com/machinebox/graphql"
 	"github.com/xxuejie/animagus/pkg/ast"
 	"github.com/xxuejie/animagus/pkg/coretypes"
 	"github.com/xxuejie/animagus/pkg/executor"
 	"github.com/xxuejie/animagus/pkg/indexer"
 	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
 	"github.com/xxuejie/animagus/pkg/verifier"
 )
 
@@ -22,19 +25,165 @@ type callInfo struct {
 	context indexer.ValueContext
 }
 
+type program struct {
+	name    string
+	hash    []byte
+	calls   map[string]callInfo
+	streams []*ast.Stream
+	store   store.Store
+	source  source.Source
+
+	mutex sync.Mutex
+	// subscriptions of running streams, they are closed once the program is
+	// replaced.
+	subscriptions map[store.Subscription]bool
+	replaced      bool
+}
+
+func (p *program) track(subscription store.Subscription) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.replaced {
+		return false
+	}
+	p.subscriptions[subscription] = true
+	return true
+}
+
+func (p *program) untrack(subscription store.Subscription) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.subscriptions, subscription)
+}
+
+func (p *program) isReplaced() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.replaced
+}
+
+func (p *program) replace() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.replaced = true
+	for subscription := range p.subscriptions {
+		subscription.Close()
+	}
+	p.subscriptions = nil
+}
+
+// Server serves calls and streams of one or more AST programs, they are
+// addressed as program/name, the program part can be omitted when only one
+// program is loaded.
 type Server struct {
-	calls         map[string]callInfo
-	streams       []*ast.Stream
-	redisPool     *redis.Pool
-	graphqlClient *graphql.Client
+	mutex    sync.RWMutex
+	programs map[string]*program
+	progress map[string]func() indexer.Progress
+}
+
+func NewServer() *Server {
+	return &Server{
+		programs: make(map[string]*program),
+		progress: make(map[string]func() indexer.Progress),
+	}
+}
+
+// AddProgram loads an AST program, s and src should be the same ones used
+// in the indexer for the program.
+func (s *Server) AddProgram(name string, astContent []byte, st store.Store, src source.Source) error {
+	if len(name) == 0 || strings.ContainsAny(name, "/:") {
+		return fmt.Errorf("Invalid program name: %s", name)
+	}
+	p, err := newProgram(name, astContent, st, src)
+	if err != nil {
+		return fmt.Errorf("Error loading program %s: %s", name, err)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, found := s.programs[name]; found {
+		return fmt.Errorf("Program %s already exists!", name)
+	}
+	s.programs[name] = p
+	return nil
+}
+
+// ReplaceProgram switches an existing program to a new AST and index, for
+// example after the program is reindexed. Running streams of the program
+// are closed so clients can subscribe to the new index.
+func (s *Server) ReplaceProgram(name string, astContent []byte, st store.Store, src source.Source) error {
+	p, err := newProgram(name, astContent, st, src)
+	if err != nil {
+		return fmt.Errorf("Error loading program %s: %s", name, err)
+	}
+	s.mutex.Lock()
+	old, found := s.programs[name]
+	if !found {
+		s.mutex.Unlock()
+		return fmt.Errorf("Program %s does not exist!", name)
+	}
+	s.programs[name] = p
+	s.mutex.Unlock()
+	old.replace()
+	return nil
+}
+
+// SetProgress sets the function reporting indexing progress of a program,
+// which is included in Status.
+func (s *Server) SetProgress(name string, progress func() indexer.Progress) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, found := s.programs[name]; !found {
+		return fmt.Errorf("Program %s does not exist!", name)
+	}
+	s.progress[name] = progress
+	return nil
+}
+
+// lookup returns the program and the name of the call or stream for a full
+// name.
+func (s *Server) lookup(fullName string) (*program, string, error) {
+	parts := strings.SplitN(fullName, "/", 2)
+	if len(parts) == 1 {
+		_, p, err := s.lookupProgram("")
+		if err != nil {
+			return nil, "", fmt.Errorf("Program name is required for %s!", fullName)
+		}
+		return p, fullName, nil
+	}
+	_, p, err := s.lookupProgram(parts[0])
+	return p, parts[1], err
+}
+
+// lookupProgram returns a program and its name, name can be empty when only
+// one program is loaded.
+func (s *Server) lookupProgram(name string) (string, *program, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if len(name) == 0 {
+		if len(s.programs) != 1 {
+			return "", nil, fmt.Errorf("Program name is required!")
+		}
+		for name, p := range s.programs {
+			return name, p, nil
+		}
+	}
+	p, found := s.programs[name]
+	if !found {
+		return "", nil, fmt.Errorf("Program %s does not exist!", name)
+	}
+	return name, p, nil
 }
 
-func NewServer(astContent []byte, redisPool *redis.Pool, graphqlUrl string) (*Server, error) {
+func newProgram(name string, astContent []byte, s store.Store, src source.Source) (*program, error) {
 	root := &ast.Root{}
 	err := proto.Unmarshal(astContent, root)
 	if err != nil {
 		return nil, err
 	}
+	hash, err := indexer.AstHash(astContent)
+	if err != nil {
+		return nil, err
+	}
 	calls := make(map[string]callInfo)
 	for _, call := range root.GetCalls() {
 		err = verifier.Verify(call.GetResult())
@@ -56,19 +205,21 @@ func NewServer(astContent []byte, redisPool *redis.Pool, graphqlUrl string) (*Se
 			return nil, fmt.Errorf("Verification failure for stream %s: %s", stream.GetName(), err)
 		}
 	}
-	client := graphql.NewClient(graphqlUrl)
-	return &Server{
+	return &program{
+		name:          name,
+		hash:          hash,
 		calls:         calls,
 		streams:       root.GetStreams(),
-		redisPool:     redisPool,
-		graphqlClient: client,
+		store:         s,
+		source:        src,
+		subscriptions: make(map[store.Subscription]bool),
 	}, nil
 }
 
 type executeEnvironment struct {
 	params       *GenericParams
 	valueContext indexer.ValueContext
-	s            *Server
+	p            *program
 }
 
 func (e executeEnvironment) ReplaceArgs(args []*ast.Value) error {
@@ -93,10 +244,6 @@ func (e executeEnvironment) IndexParam(i int, value *ast.Value) error {
 	return fmt.Errorf("Indexing param is not allowed when executing!")
 }
 
-type getCellsResponse struct {
-	GetCells []*rpctypes.OutPoint
-}
-
 func (e executeEnvironment) QueryCell(query *ast.Value) ([]*ast.Value, error) {
 	queryIndex := e.valueContext.QueryIndex(query)
 	if queryIndex == -1 {
@@ -110,95 +257,69 @@ func (e executeEnvironment) QueryCell(query *ast.Value) ([]*ast.Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	conn := e.s.redisPool.Get()
-	defer conn.Close()
-	slices, err := redis.ByteSlices(conn.Do("SMEMBERS", indexKey))
+	slices, err := e.p.store.Members(indexKey)
 	if err != nil {
 		return nil, err
 	}
+	queryCells.WithLabelValues(e.p.name, e.valueContext.Name).Observe(float64(len(slices)))
 	if len(slices) == 0 {
 		return []*ast.Value{}, nil
 	}
-	outPoints := make([]coretypes.OutPoint, len(slices))
+	outPoints := make([]rpctypes.OutPoint, len(slices))
 	for i, slice := range slices {
-		outPoints[i] = coretypes.OutPoint(slice)
-		if !outPoints[i].Verify(true) {
+		outPoint := coretypes.OutPoint(slice)
+		if !outPoint.Verify(true) {
 			return nil, fmt.Errorf("OutPoint %x verification failure!", slice)
 		}
+		outPoints[i].Index = rpctypes.Uint32(outPoint.Index())
+		copy(outPoints[i].TxHash[:], outPoint.TxHash())
 	}
-	req := graphql.NewRequest(fmt.Sprintf(`
-query {
-  getCells(outPoints: %s, skipMissing: true) {
-    cell {
-      capacity
-      lock {
-        code_hash
-        hash_type
-        args
-      }
-      type {
-        code_hash
-        hash_type
-        args
-      }
-    }
-    cell_data {
-      content
-    }
-    tx_hash
-    index
-    header {
-      compact_target
-      parent_hash
-      timestamp
-      number
-      epoch
-      transactions_root
-      proposals_hash
-      uncles_hash
-      dao
-      nonce
-    }
-  }
-}
-`, assembleQueryString(outPoints)))
-	var response getCellsResponse
-	err = e.s.graphqlClient.Run(context.Background(), req, &response)
+	started := time.Now()
+	cells, err := e.p.source.Cells(outPoints)
+	getCellsDuration.WithLabelValues(e.p.name).Observe(time.Since(started).Seconds())
 	if err != nil {
 		return nil, err
 	}
-	results := make([]*ast.Value, len(response.GetCells))
-	for i, cell := range response.GetCells {
+	results := make([]*ast.Value, len(cells))
+	for i, cell := range cells {
 		results[i] = ast.ConvertCell(*cell.GraphqlCell, *cell.GraphqlCellData.Content, *cell, cell.GraphqlHeader)
 	}
 	return results, nil
 }
 
-func assembleQueryString(outPoints []coretypes.OutPoint) string {
-	pieces := make([]string, len(outPoints))
-	for i, outPoint := range outPoints {
-		pieces[i] = fmt.Sprintf("{txHash: \"0x%x\", index: \"0x%x\"}", outPoint.TxHash(), outPoint.Index())
-	}
-	return fmt.Sprintf("[%s]", strings.Join(pieces, ", "))
-}
-
 func (s *Server) Call(ctx context.Context, p *GenericParams) (*ast.Value, error) {
-	callInfo, found := s.calls[p.GetName()]
+	program, name, err := s.lookup(p.GetName())
+	if err != nil {
+		callErrors.WithLabelValues("", "").Inc()
+		return nil, err
+	}
+	callInfo, found := program.calls[name]
 	if !found {
+		callErrors.WithLabelValues(program.name, "").Inc()
 		return nil, fmt.Errorf("Calling non-exist function: %s", p.GetName())
 	}
 	environment := executeEnvironment{
 		params:       p,
 		valueContext: callInfo.context,
-		s:            s,
+		p:            program,
+	}
+	started := time.Now()
+	value, err := executor.Execute(callInfo.expr, environment)
+	callDuration.WithLabelValues(program.name, name).Observe(time.Since(started).Seconds())
+	if err != nil {
+		callErrors.WithLabelValues(program.name, name).Inc()
 	}
-	return executor.Execute(callInfo.expr, environment)
+	return value, err
 }
 
 func (s *Server) Stream(p *GenericParams, streamServer GenericService_StreamServer) error {
+	program, name, err := s.lookup(p.GetName())
+	if err != nil {
+		return err
+	}
 	var selectedStream *ast.Stream
-	for _, aStream := range s.streams {
-		if p.GetName() == aStream.GetName() {
+	for _, aStream := range program.streams {
+		if name == aStream.GetName() {
 			selectedStream = aStream
 			break
 		}
@@ -207,35 +328,81 @@ func (s *Server) Stream(p *GenericParams, streamServer GenericService_StreamServ
 		return fmt.Errorf("Calling non-exist stream: %s", p.GetName())
 	}
 
-	psc := redis.PubSubConn{Conn: s.redisPool.Get()}
-	defer psc.Close()
-
-	key := fmt.Sprintf("STREAM:%s", selectedStream.GetName())
-	err := psc.Subscribe(key)
+	subscription, err := program.store.Subscribe(indexer.StreamKey(selectedStream.GetName()))
 	if err != nil {
 		return err
 	}
+	defer subscription.Close()
+	if !program.track(subscription) {
+		return fmt.Errorf("Program of stream %s is replaced, please retry!", p.GetName())
+	}
+	defer program.untrack(subscription)
+	subscribers := streamSubscribers.WithLabelValues(program.name, name)
+	subscribers.Inc()
+	defer subscribers.Dec()
+
 	for {
-		switch v := psc.Receive().(type) {
-		case redis.Message:
-			if v.Channel == key {
-				value := &ast.Value{}
-				err = proto.Unmarshal(v.Data, value)
-				if err != nil {
-					break
-				}
-				err = streamServer.Send(value)
-				if err == io.EOF {
-					return psc.Unsubscribe()
-				}
-				if err != nil {
-					break
-				}
+		data, err := subscription.Receive()
+		if err != nil {
+			if program.isReplaced() {
+				return fmt.Errorf("Program of stream %s is replaced, please subscribe again!", p.GetName())
 			}
-		case error:
-			return v
+			return err
+		}
+		value := &ast.Value{}
+		err = proto.Unmarshal(data, value)
+		if err != nil {
+			return err
+		}
+		err = streamServer.Send(value)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Status reports how far a program is indexed, so clients can tell whether
+// results of calls are fresh.
+func (s *Server) Status(ctx context.Context, p *StatusParams) (*Status, error) {
+	name, program, err := s.lookupProgram(p.GetProgram())
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{
+		Program: name,
+		AstHash: program.hash,
+	}
+	lastBlock, err := program.store.Get("LAST_BLOCK")
+	if err != nil {
+		return nil, err
+	}
+	if len(lastBlock) == 40 {
+		status.IndexedBlockNumber = binary.LittleEndian.Uint64(lastBlock)
+		status.IndexedBlockHash = lastBlock[8:]
+	}
+	if tipSource, ok := program.source.(source.TipSource); ok {
+		status.TipBlockNumber, err = tipSource.TipBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+		status.TipKnown = true
+		if status.TipBlockNumber > status.IndexedBlockNumber {
+			status.Lag = status.TipBlockNumber - status.IndexedBlockNumber
+		}
+	}
+	s.mutex.RLock()
+	progress := s.progress[name]
+	s.mutex.RUnlock()
+	if progress != nil {
+		current := progress()
+		status.BlocksPerSecond = current.BlocksPerSecond
+		if !current.LastReorgTime.IsZero() {
+			status.LastReorgBlockNumber = current.LastReorgBlockNumber
+			status.LastReorgTime = current.LastReorgTime.Unix()
 		}
 	}
-	psc.Unsubscribe()
-	return err
+	return status, nil
 }
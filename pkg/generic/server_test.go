@@ -0,0 +1,587 @@
+package generic
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/xxuejie/animagus/pkg/ast"
+	"github.com/xxuejie/animagus/pkg/indexer"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
+	"google.golang.org/grpc"
+)
+
+func arg(i uint64) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_ARG,
+		Primitive: &ast.Value_U{
+			U: i,
+		},
+	}
+}
+
+func param(i uint64) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_PARAM,
+		Primitive: &ast.Value_U{
+			U: i,
+		},
+	}
+}
+
+func uint_value(u uint64) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_UINT64,
+		Primitive: &ast.Value_U{
+			U: u,
+		},
+	}
+}
+
+func bytes_value(b []byte) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_BYTES,
+		Primitive: &ast.Value_Raw{
+			Raw: b,
+		},
+	}
+}
+
+func getCapacity(value *ast.Value) *ast.Value {
+	return &ast.Value{
+		T:        ast.Value_GET_CAPACITY,
+		Children: []*ast.Value{value},
+	}
+}
+
+func testAst(t *testing.T) []byte {
+	cells := &ast.Value{
+		T: ast.Value_QUERY_CELLS,
+		Children: []*ast.Value{
+			&ast.Value{
+				T: ast.Value_EQUAL,
+				Children: []*ast.Value{
+					&ast.Value{
+						T: ast.Value_GET_ARGS,
+						Children: []*ast.Value{
+							&ast.Value{
+								T:        ast.Value_GET_LOCK,
+								Children: []*ast.Value{arg(0)},
+							},
+						},
+					},
+					param(0),
+				},
+			},
+		},
+	}
+	balance := &ast.Value{
+		T: ast.Value_REDUCE,
+		Children: []*ast.Value{
+			&ast.Value{
+				T:        ast.Value_ADD,
+				Children: []*ast.Value{arg(0), arg(1)},
+			},
+			uint_value(0),
+			&ast.Value{
+				T:        ast.Value_MAP,
+				Children: []*ast.Value{getCapacity(arg(0)), cells},
+			},
+		},
+	}
+	inserts := &ast.Value{
+		T: ast.Value_COND,
+		Children: []*ast.Value{
+			&ast.Value{
+				T: ast.Value_AND,
+				Children: []*ast.Value{
+					&ast.Value{
+						T:        ast.Value_EQUAL,
+						Children: []*ast.Value{arg(1), bytes_value([]byte("insert"))},
+					},
+					&ast.Value{
+						T:        ast.Value_EQUAL,
+						Children: []*ast.Value{arg(2), bytes_value([]byte("index"))},
+					},
+				},
+			},
+			getCapacity(arg(0)),
+			&ast.Value{T: ast.Value_NIL},
+		},
+	}
+	root := &ast.Root{
+		Calls: []*ast.Call{
+			&ast.Call{
+				Name:   "balance",
+				Result: balance,
+			},
+		},
+		Streams: []*ast.Stream{
+			&ast.Stream{
+				Name:   "inserts",
+				Filter: inserts,
+			},
+		},
+	}
+	content, err := proto.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func testTx(inputs []rpctypes.OutPoint, capacity uint64, args byte) rpctypes.Transaction {
+	tx := rpctypes.Transaction{}
+	for _, input := range inputs {
+		tx.Inputs = append(tx.Inputs, rpctypes.CellInput{PreviousOutput: input})
+	}
+	tx.Outputs = []rpctypes.CellOutput{
+		rpctypes.CellOutput{
+			Capacity: rpctypes.Uint64(capacity),
+			Lock: rpctypes.Script{
+				HashType: rpctypes.Data,
+				Args:     rpctypes.Bytes{args},
+			},
+		},
+	}
+	tx.OutputsData = []rpctypes.Bytes{rpctypes.Bytes{}}
+	return tx
+}
+
+func outPoint(block *rpctypes.BlockView) rpctypes.OutPoint {
+	return rpctypes.OutPoint{
+		TxHash: block.Transactions[0].Hash,
+		Index:  0,
+	}
+}
+
+type testStreamServer struct {
+	grpc.ServerStream
+	values chan *ast.Value
+	limit  int
+}
+
+func (s *testStreamServer) Send(value *ast.Value) error {
+	s.values <- value
+	s.limit--
+	if s.limit == 0 {
+		return io.EOF
+	}
+	return nil
+}
+
+func TestCallAndStream(t *testing.T) {
+	src := source.NewMemorySource()
+	s := store.NewMemoryStore()
+	content := testAst(t)
+	i, err := indexer.NewIndexer(content, s, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer()
+	err = server.AddProgram("test", content, s, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streamServer := &testStreamServer{
+		values: make(chan *ast.Value, 10),
+		limit:  3,
+	}
+	streamResult := make(chan error, 1)
+	go func() {
+		streamResult <- server.Stream(&GenericParams{Name: "inserts"}, streamServer)
+	}()
+	for j := 0; s.Subscribers(indexer.StreamKey("inserts")) == 0; j++ {
+		if j >= 100 {
+			t.Fatal("Stream is not subscribed!")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	block0, err := src.AppendTransactions(testTx(nil, 100, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.AppendTransactions(testTx(nil, 200, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.AppendTransactions(testTx([]rpctypes.OutPoint{outPoint(block0)}, 70, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for args, expected := range map[byte]uint64{1: 200, 2: 70, 3: 0} {
+		value, err := server.Call(context.Background(), &GenericParams{
+			Name:   "balance",
+			Params: []*ast.Value{bytes_value([]byte{args})},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value.GetU() != expected {
+			t.Errorf("Invalid balance for args %x: %d, expected: %d", args, value.GetU(), expected)
+		}
+	}
+
+	for _, expected := range []uint64{100, 200, 70} {
+		value := <-streamServer.values
+		if value.GetU() != expected {
+			t.Errorf("Invalid stream value: %d, expected: %d", value.GetU(), expected)
+		}
+	}
+	err = <-streamResult
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Subscribers(indexer.StreamKey("inserts")) != 0 {
+		t.Error("Stream is not unsubscribed!")
+	}
+
+	// Cells indexed but no longer live in the source are skipped, the
+	// indexer has not caught up with the rollback yet.
+	src.Rollback(1)
+	value, err := server.Call(context.Background(), &GenericParams{
+		Name:   "balance",
+		Params: []*ast.Value{bytes_value([]byte{1})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.GetU() != 0 {
+		t.Errorf("Invalid balance: %d, expected: %d", value.GetU(), 0)
+	}
+}
+
+func TestCallMissing(t *testing.T) {
+	server := NewServer()
+	err := server.AddProgram("test", testAst(t), store.NewMemoryStore(), source.NewMemorySource())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.Call(context.Background(), &GenericParams{Name: "missing"})
+	if err == nil {
+		t.Error("Calling a missing function should fail!")
+	}
+	err = server.Stream(&GenericParams{Name: "missing"}, &testStreamServer{})
+	if err == nil {
+		t.Error("Streaming a missing stream should fail!")
+	}
+}
+
+func TestMultiplePrograms(t *testing.T) {
+	src := source.NewMemorySource()
+	s := store.NewMemoryStore()
+	server := NewServer()
+	indexers := make(map[string]*indexer.Indexer)
+	for _, name := range []string{"a", "b"} {
+		programStore := store.NewPrefixStore(s, indexer.ProgramKeyPrefix(name))
+		i, err := indexer.NewIndexer(testAst(t), programStore, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		indexers[name] = i
+		err = server.AddProgram(name, testAst(t), programStore, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := server.AddProgram("a", testAst(t), s, src)
+	if err == nil {
+		t.Error("Adding an existing program should fail!")
+	}
+	err = server.AddProgram("c/d", testAst(t), s, src)
+	if err == nil {
+		t.Error("Adding a program with invalid name should fail!")
+	}
+
+	_, err = src.AppendTransactions(testTx(nil, 100, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = indexers["a"].Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.AppendTransactions(testTx(nil, 200, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = indexers["b"].Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cells not indexed yet by a program are not included
+	for name, expected := range map[string]uint64{"a/balance": 100, "b/balance": 300} {
+		value, err := server.Call(context.Background(), &GenericParams{
+			Name:   name,
+			Params: []*ast.Value{bytes_value([]byte{1})},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value.GetU() != expected {
+			t.Errorf("Invalid balance for %s: %d, expected: %d", name, value.GetU(), expected)
+		}
+	}
+	for _, name := range []string{"balance", "c/balance", "a/missing"} {
+		_, err = server.Call(context.Background(), &GenericParams{Name: name})
+		if err == nil {
+			t.Errorf("Calling %s should fail!", name)
+		}
+	}
+}
+
+func TestReplaceProgram(t *testing.T) {
+	src := source.NewMemorySource()
+	_, err := src.AppendTransactions(testTx(nil, 100, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewMemoryStore()
+	oldStore := store.NewPrefixStore(s, "old:")
+	newStore := store.NewPrefixStore(s, "new:")
+	server := NewServer()
+	err = server.AddProgram("test", testAst(t), oldStore, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := indexer.NewIndexer(testAst(t), newStore, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streamResult := make(chan error, 1)
+	go func() {
+		streamResult <- server.Stream(&GenericParams{Name: "inserts"}, &testStreamServer{})
+	}()
+	for j := 0; s.Subscribers("old:"+indexer.StreamKey("inserts")) == 0; j++ {
+		if j >= 100 {
+			t.Fatal("Stream is not subscribed!")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	err = server.ReplaceProgram("test", testAst(t), newStore, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = <-streamResult
+	if err == nil {
+		t.Error("Stream of replaced program should be closed!")
+	}
+	value, err := server.Call(context.Background(), &GenericParams{
+		Name:   "test/balance",
+		Params: []*ast.Value{bytes_value([]byte{1})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.GetU() != 100 {
+		t.Errorf("Invalid balance: %d, expected: %d", value.GetU(), 100)
+	}
+	err = server.ReplaceProgram("missing", testAst(t), newStore, src)
+	if err == nil {
+		t.Error("Replacing a missing program should fail!")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	src := source.NewMemorySource()
+	s := store.NewMemoryStore()
+	i, err := indexer.NewIndexer(testAst(t), s, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer()
+	err = server.AddProgram("test", testAst(t), s, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.SetProgress("test", i.Progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < 3; j++ {
+		_, err = src.AppendTransactions(testTx(nil, 100, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexed := src.Tip()
+	_, err = src.AppendTransactions(testTx(nil, 100, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := server.Status(context.Background(), &StatusParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := indexer.AstHash(testAst(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.GetProgram() != "test" || !bytes.Equal(status.GetAstHash(), hash) {
+		t.Errorf("Invalid program: %s, AST hash: %x", status.GetProgram(), status.GetAstHash())
+	}
+	if status.GetIndexedBlockNumber() != 2 || !bytes.Equal(status.GetIndexedBlockHash(), indexed.Header.Hash[:]) {
+		t.Errorf("Invalid indexed block: %d, hash: %x", status.GetIndexedBlockNumber(), status.GetIndexedBlockHash())
+	}
+	if !status.GetTipKnown() || status.GetTipBlockNumber() != 3 || status.GetLag() != 1 {
+		t.Errorf("Invalid tip: %d, lag: %d", status.GetTipBlockNumber(), status.GetLag())
+	}
+	if status.GetBlocksPerSecond() <= 0 || status.GetLastReorgTime() != 0 {
+		t.Errorf("Invalid progress: %v", status)
+	}
+
+	src.Rollback(2)
+	for j := 0; j < 3; j++ {
+		_, err = src.AppendTransactions(testTx(nil, 200, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err = server.Status(context.Background(), &StatusParams{Program: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.GetIndexedBlockNumber() != 4 || status.GetLag() != 0 {
+		t.Errorf("Invalid indexed block: %d, lag: %d", status.GetIndexedBlockNumber(), status.GetLag())
+	}
+	if status.GetLastReorgBlockNumber() != 2 || status.GetLastReorgTime() == 0 {
+		t.Errorf("Invalid last reorg: %d, time: %d", status.GetLastReorgBlockNumber(), status.GetLastReorgTime())
+	}
+
+	_, err = server.Status(context.Background(), &StatusParams{Program: "missing"})
+	if err == nil {
+		t.Error("Status of missing program should fail!")
+	}
+
+	// Sources without TipSource do not report the chain tip
+	err = server.AddProgram("notip", testAst(t), s, struct{ source.Source }{src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err = server.Status(context.Background(), &StatusParams{Program: "notip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.GetTipKnown() || status.GetTipBlockNumber() != 0 || status.GetLag() != 0 {
+		t.Errorf("Unknown tip is reported: %v", status)
+	}
+	if status.GetIndexedBlockNumber() != 4 {
+		t.Errorf("Invalid indexed block: %d", status.GetIndexedBlockNumber())
+	}
+}
+
+func histogram(t *testing.T, observer prometheus.Observer) *dto.Histogram {
+	metric := &dto.Metric{}
+	err := observer.(prometheus.Metric).Write(metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram()
+}
+
+func TestMetrics(t *testing.T) {
+	src := source.NewMemorySource()
+	s := store.NewMemoryStore()
+	i, err := indexer.NewIndexer(testAst(t), s, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer()
+	err = server.AddProgram("metrics", testAst(t), s, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.AppendTransactions(testTx(nil, 100, 1), testTx(nil, 200, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = server.Call(context.Background(), &GenericParams{
+		Name:   "metrics/balance",
+		Params: []*ast.Value{bytes_value([]byte{1})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := histogram(t, callDuration.WithLabelValues("metrics", "balance")).GetSampleCount(); n != 1 {
+		t.Errorf("Invalid number of calls: %d", n)
+	}
+	if sum := histogram(t, queryCells.WithLabelValues("metrics", "balance")).GetSampleSum(); sum != 2 {
+		t.Errorf("Invalid number of queried cells: %f", sum)
+	}
+	if n := histogram(t, getCellsDuration.WithLabelValues("metrics")).GetSampleCount(); n != 1 {
+		t.Errorf("Invalid number of cell resolutions: %d", n)
+	}
+	_, err = server.Call(context.Background(), &GenericParams{Name: "metrics/missing"})
+	if err == nil {
+		t.Fatal("Calling missing function should fail!")
+	}
+	if n := testutil.ToFloat64(callErrors.WithLabelValues("metrics", "")); n != 1 {
+		t.Errorf("Invalid number of call errors: %f", n)
+	}
+
+	subscribers := streamSubscribers.WithLabelValues("metrics", "inserts")
+	streamServer := &testStreamServer{
+		values: make(chan *ast.Value, 10),
+		limit:  1,
+	}
+	streamResult := make(chan error, 1)
+	go func() {
+		streamResult <- server.Stream(&GenericParams{Name: "metrics/inserts"}, streamServer)
+	}()
+	for j := 0; testutil.ToFloat64(subscribers) != 1; j++ {
+		if j >= 100 {
+			t.Fatal("Stream subscriber is not counted!")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err = src.AppendTransactions(testTx(nil, 300, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = <-streamResult
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.ToFloat64(subscribers); n != 0 {
+		t.Errorf("Invalid number of stream subscribers: %f", n)
+	}
+}
@@ -0,0 +1,267 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+// bootstrapped turns a store indexed from the genesis block into the
+// expected store of an index bootstrapped at blockNumber.
+func bootstrapped(t *testing.T, i *Indexer, s store.Store, src source.Source, blockNumber uint64) {
+	block, _ := src.Block(blockNumber)
+	lastBlock := make([]byte, 40)
+	binary.LittleEndian.PutUint64(lastBlock, blockNumber)
+	copy(lastBlock[8:], block.Header.Hash[:])
+	batch := &store.Batch{}
+	batch.Set("AST_HASH", append(append([]byte{}, i.hash...), lastBlock...))
+	for n := uint64(0); n < blockNumber; n++ {
+		batch.Delete(fmt.Sprintf("BLOCK:%d:HASH", n))
+	}
+	err := s.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteRevertCommands(t, s, 0, blockNumber)
+}
+
+func TestBootstrap(t *testing.T) {
+	src := testChain(t, 10)
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, src)
+	i.SetStartBlock(6)
+	i.SetBootstrap(true)
+	i.SetPrefetch(4)
+	err := i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrapped(t, i, expected, src, 5)
+	assertSameStore(t, s, expected, "")
+	dbHash, _ := s.Get("AST_HASH")
+	if len(dbHash) != 72 || binary.LittleEndian.Uint64(dbHash[32:]) != 5 {
+		t.Errorf("Bootstrap block is not recorded: %x", dbHash)
+	}
+
+	// Cells live before the start block can be spent later
+	block3, _ := src.Block(3)
+	_, err = src.AppendTransactions(testTx([]rpctypes.OutPoint{outPoint(block3, 0, 1)}, testOutput{1, 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrapped(t, i, expected, src, 5)
+	assertSameStore(t, s, expected, "")
+	var outPoints []rpctypes.OutPoint
+	for n := uint64(1); n < 10; n++ {
+		if n != 3 {
+			block, _ := src.Block(n)
+			outPoints = append(outPoints, outPoint(block, 0, 1))
+		}
+	}
+	assertCells(t, i, s, 4, outPoints...)
+
+	// Restarting an index does not bootstrap again
+	i = newTestIndexer(t, s, src)
+	i.SetStartBlock(8)
+	i.SetBootstrap(true)
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameStore(t, s, expected, "")
+}
+
+func TestBootstrapRpcSource(t *testing.T) {
+	src := testChain(t, 8)
+	server := newTestRpcServer(t, src)
+	defer server.Close()
+
+	s := store.NewMemoryStore()
+	rpcSource, err := source.NewRpcSource(server.URL, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := newTestIndexer(t, s, rpcSource)
+	i.SetStartBlock(4)
+	i.SetBootstrap(true)
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := store.NewMemoryStore()
+	expectedSource, err := source.NewRpcSource(server.URL, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = newTestIndexer(t, expected, expectedSource).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Live cells are stored as well
+	bootstrapped(t, i, expected, src, 3)
+	assertSameStore(t, s, expected, "")
+}
+
+// reorgingSource reorganizes the chain from block forkAt when block
+// forkAt+1 is requested the first time.
+type reorgingSource struct {
+	*source.MemorySource
+	t       *testing.T
+	forkAt  uint64
+	reorged bool
+}
+
+func (s *reorgingSource) Block(blockNumber uint64) (*rpctypes.BlockView, error) {
+	if blockNumber == s.forkAt+1 && !s.reorged {
+		s.reorged = true
+		s.Rollback(s.forkAt)
+		for j := 0; j < 3; j++ {
+			_, err := s.AppendTransactions(testTx(nil, testOutput{uint64(j), 7}))
+			if err != nil {
+				s.t.Fatal(err)
+			}
+		}
+	}
+	return s.MemorySource.Block(blockNumber)
+}
+
+func TestBootstrapReorg(t *testing.T) {
+	src := testChain(t, 6)
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, &reorgingSource{MemorySource: src, t: t, forkAt: 2})
+	i.SetStartBlock(5)
+	i.SetBootstrap(true)
+	i.SetBulkSync(0, 2)
+	err := i.Sync()
+	if err == nil || !strings.Contains(err.Error(), "reorganized") {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Blocks before the fork are committed in the first batch, they are
+	// cleared as well.
+	keys := s.Keys()
+	if len(keys) != 1 || keys[0] != "AST_HASH" {
+		t.Errorf("Index is bootstrapped across a reorg: %v", keys)
+	}
+
+	// Retrying bootstraps on the new chain
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrapped(t, i, expected, src, 4)
+	assertSameStore(t, s, expected, "")
+}
+
+func TestBootstrapUnavailableBlock(t *testing.T) {
+	src := testChain(t, 3)
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, src)
+	i.SetStartBlock(6)
+	i.SetBootstrap(true)
+	err := i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastBlock, _ := s.Get("LAST_BLOCK")
+	if len(lastBlock) != 40 || binary.LittleEndian.Uint64(lastBlock) != 2 {
+		t.Errorf("Available blocks are not bootstrapped: %x", lastBlock)
+	}
+	dbHash, _ := s.Get("AST_HASH")
+	if !bytes.Equal(dbHash, i.hash) {
+		t.Errorf("Invalid AST hash: %x", dbHash)
+	}
+
+	// Bootstrapping continues once more blocks are available
+	for n := 0; n < 4; n++ {
+		_, err = src.AppendTransactions(testTx(nil, testOutput{uint64(n), 5}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrapped(t, i, expected, src, 5)
+	assertSameStore(t, s, expected, "")
+}
+
+// flakySource fails the first time block failAt is requested.
+type flakySource struct {
+	*source.MemorySource
+	failAt uint64
+	failed bool
+}
+
+func (s *flakySource) Block(blockNumber uint64) (*rpctypes.BlockView, error) {
+	if blockNumber == s.failAt && !s.failed {
+		s.failed = true
+		return nil, fmt.Errorf("Block %d is not available!", blockNumber)
+	}
+	return s.MemorySource.Block(blockNumber)
+}
+
+func TestBootstrapResume(t *testing.T) {
+	src := testChain(t, 8)
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, &flakySource{MemorySource: src, failAt: 5})
+	i.SetStartBlock(7)
+	i.SetBootstrap(true)
+	i.SetBulkSync(0, 2)
+	err := i.Sync()
+	if err == nil {
+		t.Fatal("Source error should be returned!")
+	}
+	bootstrapBlock, _ := s.Get(bootstrapBlockKey)
+	if len(bootstrapBlock) != 8 || binary.LittleEndian.Uint64(bootstrapBlock) != 6 {
+		t.Errorf("Bootstrap block is not kept: %x", bootstrapBlock)
+	}
+
+	// A restarted index resumes bootstrapping even if the start block
+	// changes.
+	i = newTestIndexer(t, s, src)
+	i.SetStartBlock(3)
+	i.SetBootstrap(true)
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrapped(t, i, expected, src, 6)
+	assertSameStore(t, s, expected, "")
+}
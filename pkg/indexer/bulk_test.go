@@ -0,0 +1,129 @@
+package indexer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+type countingStore struct {
+	*store.MemoryStore
+	commits int
+}
+
+func (s *countingStore) Commit(batch *store.Batch) error {
+	s.commits++
+	return s.MemoryStore.Commit(batch)
+}
+
+// deleteRevertCommands removes revert commands of blocks from start to end,
+// which are not kept for bulk indexed blocks.
+func deleteRevertCommands(t *testing.T, s store.Store, start uint64, end uint64) {
+	batch := &store.Batch{}
+	for n := start; n <= end; n++ {
+		batch.Delete(fmt.Sprintf("BLOCK:%d:REVERT_COMMANDS", n))
+	}
+	err := s.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkSync(t *testing.T) {
+	src := testChain(t, 20)
+	s := &countingStore{MemoryStore: store.NewMemoryStore()}
+	i := newTestIndexer(t, s, src)
+	i.SetBulkSync(5, 4)
+	err := i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// AST hash, 4 batches for block 0 to 14, and one commit per block for
+	// the last 5 blocks.
+	if s.commits != 10 {
+		t.Errorf("Invalid number of commits: %d", s.commits)
+	}
+	expected := store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteRevertCommands(t, expected, 0, 14)
+	assertSameStore(t, s.MemoryStore, expected, "")
+
+	// Blocks close to the tip can still be reverted
+	src.Rollback(17)
+	for j := 0; j < 4; j++ {
+		_, err = src.AppendTransactions(testTx(nil, testOutput{uint64(j), 5}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteRevertCommands(t, expected, 0, 14)
+	assertSameStore(t, s.MemoryStore, expected, "")
+}
+
+func TestBulkSyncRpcSource(t *testing.T) {
+	src := testChain(t, 12)
+	server := newTestRpcServer(t, src)
+	defer server.Close()
+
+	s := store.NewMemoryStore()
+	rpcSource, err := source.NewRpcSource(server.URL, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := newTestIndexer(t, s, rpcSource)
+	i.SetPrefetch(4)
+	i.SetBulkSync(2, 100)
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := store.NewMemoryStore()
+	expectedSource, err := source.NewRpcSource(server.URL, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = newTestIndexer(t, expected, expectedSource).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cells created in one block and spent in a later block of the same
+	// batch are resolved from the pending batch.
+	deleteRevertCommands(t, expected, 0, 9)
+	assertSameStore(t, s, expected, "")
+	var outPoints []rpctypes.OutPoint
+	for n := uint64(1); n < 12; n++ {
+		block, _ := src.Block(n)
+		outPoints = append(outPoints, outPoint(block, 0, 1))
+	}
+	assertCells(t, i, s, 4, outPoints...)
+}
+
+func TestBulkSyncWithoutTipSource(t *testing.T) {
+	src := testChain(t, 5)
+	s := &countingStore{MemoryStore: store.NewMemoryStore()}
+	i := newTestIndexer(t, s, struct{ source.Source }{src})
+	i.SetBulkSync(1, 10)
+	err := i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.commits != 6 {
+		t.Errorf("Blocks should be indexed one by one, commits: %d", s.commits)
+	}
+}
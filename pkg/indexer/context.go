@@ -6,6 +6,7 @@ import (
 	"sort"
 
 	"github.com/golang/protobuf/proto"
+	blake2b "github.com/minio/blake2b-simd"
 	"github.com/xxuejie/animagus/pkg/ast"
 )
 
@@ -14,6 +15,38 @@ type ValueContext struct {
 	Value       *ast.Value
 	Queries     []*ast.Value
 	QueryParams [][]int
+	// Fingerprints identify queries regardless of the AST containing them,
+	// so index sets of unchanged queries can be reused when the AST changes.
+	Fingerprints []string
+}
+
+// QueryFingerprint returns the canonical hash of a QUERY_CELLS value, it
+// covers the indexer version as well.
+func QueryFingerprint(query *ast.Value) (string, error) {
+	buffer := proto.NewBuffer(nil)
+	buffer.SetDeterministic(true)
+	err := buffer.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+	blake2bHash := blake2b.New256()
+	_, err = blake2bHash.Write(buffer.Bytes())
+	if err != nil {
+		return "", err
+	}
+	_, err = blake2bHash.Write([]byte(Version))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", blake2bHash.Sum(nil)), nil
+}
+
+// queryKeysPrefix is the prefix of index sets of all queries.
+const queryKeysPrefix = "QUERY:"
+
+// QueryKeyPrefix is the prefix of all index sets of a query.
+func QueryKeyPrefix(fingerprint string) string {
+	return fmt.Sprintf("%s%s:", queryKeysPrefix, fingerprint)
 }
 
 func NewValueContext(name string, value *ast.Value) (ValueContext, error) {
@@ -64,7 +97,7 @@ func (c ValueContext) IndexKey(queryIndex int, paramValues map[int]*ast.Value) (
 		}
 	}
 	paramKey := string(buffer.Bytes())
-	return fmt.Sprintf("CALL:%s:QUERY:%d:PARAM:%s:CELLS", c.Name, queryIndex, paramKey), nil
+	return fmt.Sprintf("%sPARAM:%s:CELLS", QueryKeyPrefix(c.Fingerprints[queryIndex]), paramKey), nil
 }
 
 func visitValue(value *ast.Value, context *ValueContext) error {
@@ -81,8 +114,13 @@ func visitValue(value *ast.Value, context *ValueContext) error {
 			params = append(params, k)
 		}
 		sort.Ints(params)
+		fingerprint, err := QueryFingerprint(value)
+		if err != nil {
+			return err
+		}
 		context.Queries = append(context.Queries, value)
 		context.QueryParams = append(context.QueryParams, params)
+		context.Fingerprints = append(context.Fingerprints, fingerprint)
 		return nil
 	}
 	for _, child := range value.GetChildren() {
@@ -2,50 +2,79 @@ package indexer
 
 import (
 	"bytes"
-	"compress/gzip"
-	"context"
 	"encoding/binary"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/golang/protobuf/proto"
-	"github.com/gomodule/redigo/redis"
-	"github.com/machinebox/graphql"
 	blake2b "github.com/minio/blake2b-simd"
 	"github.com/xxuejie/animagus/pkg/ast"
 	"github.com/xxuejie/animagus/pkg/executor"
 	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
 	"github.com/xxuejie/animagus/pkg/verifier"
 )
 
-const Version string = "0.0.1"
+const Version string = "0.0.3"
+
+const (
+	// bootstrapBlockKey keeps the bootstrap block while bootstrapping.
+	bootstrapBlockKey = "BOOTSTRAP_BLOCK"
+	// defaultBootstrapBatchSize is used when no bulk sync batch size is set.
+	defaultBootstrapBatchSize uint64 = 500
+)
+
+type indexQuery struct {
+	context     ValueContext
+	queryIndex  int
+	fingerprint string
+}
 
 type Indexer struct {
-	hash          []byte
-	values        []ValueContext
-	streams       []*ast.Stream
-	redisPool     *redis.Pool
-	graphqlClient *graphql.Client
+	hash    []byte
+	values  []ValueContext
+	streams []*ast.Stream
+	store   store.Store
+	source  source.Source
+	queries []indexQuery
+	// skippedQueries are kept up to date by another indexer.
+	skippedQueries map[string]bool
+	// name is only used in logs.
+	name string
+	// storeCells is set for sources requiring live cells to be stored.
+	storeCells bool
+	startBlock uint64
+	// bootstrapCells is set when cells live before the start block are
+	// bootstrapped.
+	bootstrapCells bool
+	prefetcher     *prefetcher
+	// bulkSync is kept on till the indexer gets within bulkDistance blocks
+	// of the chain tip.
+	bulkSync      bool
+	bulkDistance  uint64
+	bulkBatchSize uint64
+	bulkTip       uint64
+	// reorgDepth is the number of latest blocks keeping revert commands,
+	// pruned is set once revert commands of older blocks are deleted.
+	reorgDepth uint64
+	pruned     bool
+	progress   progressMeter
+	// reverting is set while blocks of a reorg are being reverted.
+	reverting bool
 }
 
-func NewIndexer(astContent []byte, redisPool *redis.Pool, graphqlUrl string) (*Indexer, error) {
+func NewIndexer(astContent []byte, s store.Store, src source.Source) (*Indexer, error) {
 	root := &ast.Root{}
 	err := proto.Unmarshal(astContent, root)
 	if err != nil {
 		return nil, err
 	}
-	blake2bHash := blake2b.New256()
-	_, err = blake2bHash.Write(astContent)
+	hash, err := AstHash(astContent)
 	if err != nil {
 		return nil, err
 	}
-	_, err = blake2bHash.Write([]byte(Version))
-	if err != nil {
-		return nil, err
-	}
-	hash := blake2bHash.Sum(nil)
 	values := make([]ValueContext, len(root.GetCalls()))
 	for i, call := range root.GetCalls() {
 		err = verifier.Verify(call.GetResult())
@@ -64,73 +93,247 @@ func NewIndexer(astContent []byte, redisPool *redis.Pool, graphqlUrl string) (*I
 			return nil, fmt.Errorf("Verification failure for stream %s: %s", stream.GetName(), err)
 		}
 	}
-	// Test GraphQL query
-	client := graphql.NewClient(graphqlUrl)
-	// client.Log = func(s string) {
-	// 	fmt.Printf("GraphQL log: %s\n", s)
-	// }
-	err = client.Run(context.Background(), graphql.NewRequest(`
-query {
-  apiVersion
+	// The same query in different calls is only indexed once.
+	var queries []indexQuery
+	fingerprints := make(map[string]bool)
+	for _, valueContext := range values {
+		for queryIndex, fingerprint := range valueContext.Fingerprints {
+			if fingerprints[fingerprint] {
+				continue
+			}
+			fingerprints[fingerprint] = true
+			queries = append(queries, indexQuery{
+				context:     valueContext,
+				queryIndex:  queryIndex,
+				fingerprint: fingerprint,
+			})
+		}
+	}
+	indexer := &Indexer{
+		values:  values,
+		queries: queries,
+		hash:    hash,
+		store:   s,
+		source:  src,
+		streams: root.GetStreams(),
+	}
+	_, indexer.storeCells = src.(source.StoredCellSource)
+	return indexer, nil
 }
-`), nil)
+
+// AstHash returns the hash identifying the index built for an AST, it
+// covers the indexer version as well.
+func AstHash(astContent []byte) ([]byte, error) {
+	blake2bHash := blake2b.New256()
+	_, err := blake2bHash.Write(astContent)
 	if err != nil {
 		return nil, err
 	}
-	return &Indexer{
-		values:        values,
-		hash:          hash,
-		redisPool:     redisPool,
-		graphqlClient: client,
-		streams:       root.GetStreams(),
-	}, nil
+	_, err = blake2bHash.Write([]byte(Version))
+	if err != nil {
+		return nil, err
+	}
+	return blake2bHash.Sum(nil), nil
+}
+
+// SetName sets the program name of the indexer.
+func (i *Indexer) SetName(name string) {
+	i.name = name
+}
+
+func (i *Indexer) logf(format string, v ...interface{}) {
+	if len(i.name) > 0 {
+		format = "Program %s: " + format
+		v = append([]interface{}{i.name}, v...)
+	}
+	log.Printf(format, v...)
+}
+
+// Fingerprints returns fingerprints of all queries indexed.
+func (i *Indexer) Fingerprints() []string {
+	fingerprints := make([]string, len(i.queries))
+	for j, query := range i.queries {
+		fingerprints[j] = query.fingerprint
+	}
+	return fingerprints
+}
+
+// SkipQueries stops indexing queries with given fingerprints, which are
+// indexed by another indexer into the same keys.
+func (i *Indexer) SkipQueries(fingerprints []string) {
+	i.skippedQueries = make(map[string]bool)
+	for _, fingerprint := range fingerprints {
+		i.skippedQueries[fingerprint] = true
+	}
 }
 
-type getBlockResponse struct {
-	GetBlock *rpctypes.BlockView
+// SetStartBlock sets the first block to index when the store is empty.
+// Cells created before the start block are not indexed unless bootstrap is
+// enabled.
+func (i *Indexer) SetStartBlock(blockNumber uint64) {
+	i.startBlock = blockNumber
 }
 
+// SetBootstrap enables bootstrapping cells live right before the start
+// block into an empty index, by indexing blocks from the genesis block
+// without revert commands or stream values.
+func (i *Indexer) SetBootstrap(enabled bool) {
+	i.bootstrapCells = enabled
+}
+
+// SetPrefetch enables fetching up to depth blocks concurrently, blocks are
+// still indexed one by one in order.
+func (i *Indexer) SetPrefetch(depth uint64) {
+	if depth > 1 {
+		i.prefetcher = newPrefetcher(i.source, depth)
+	} else {
+		i.prefetcher = nil
+	}
+}
+
+// SetBulkSync enables bulk sync for sources implementing TipSource: blocks
+// at least distance blocks behind the chain tip are considered final, they
+// are indexed batchSize blocks per commit without revert commands. Indexer
+// switches to indexing blocks one by one once it gets close to the tip. A
+// distance of 0 disables bulk sync.
+func (i *Indexer) SetBulkSync(distance uint64, batchSize uint64) {
+	i.bulkDistance = distance
+	i.bulkBatchSize = batchSize
+	if i.bulkBatchSize == 0 {
+		i.bulkBatchSize = 1
+	}
+	_, tipSource := i.source.(source.TipSource)
+	i.bulkSync = distance > 0 && tipSource
+}
+
+// SetReorgDepth only keeps revert commands of the latest depth blocks, older
+// blocks are considered final and a reorg reverting them results in an
+// error. A depth of 0 keeps revert commands of all blocks.
+func (i *Indexer) SetReorgDepth(depth uint64) {
+	i.reorgDepth = depth
+	i.pruned = false
+}
+
+// Progress returns current indexing progress, it can be called while the
+// indexer is running.
+func (i *Indexer) Progress() Progress {
+	return i.progress.progress(time.Now())
+}
+
+func (i *Indexer) fetchBlock(blockNumber uint64) (*rpctypes.BlockView, error) {
+	var block *rpctypes.BlockView
+	var err error
+	if i.prefetcher != nil {
+		block, err = i.prefetcher.fetch(blockNumber)
+	} else {
+		block, err = i.source.Block(blockNumber)
+	}
+	return block, i.sourceError(err)
+}
+
+// Run keeps indexing new blocks from the source.
 func (i *Indexer) Run() error {
-	redisConn := i.redisPool.Get()
-	defer redisConn.Close()
+	for {
+		err := i.Sync()
+		if err != nil {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
 
-	dbHash, err := redis.Bytes(redisConn.Do("GET", "AST_HASH"))
-	if err != nil && err != redis.ErrNil {
+// Sync indexes blocks till no more blocks are available from the source.
+func (i *Indexer) Sync() error {
+	dbHash, err := i.store.Get("AST_HASH")
+	if err != nil {
 		return err
 	}
 	if len(dbHash) == 0 {
 		dbHash = i.hash
-		_, err = redisConn.Do("SET", "AST_HASH", dbHash)
+		batch := &store.Batch{}
+		batch.Set("AST_HASH", dbHash)
+		err = i.store.Commit(batch)
 		if err != nil {
 			return err
 		}
 	}
-	if !bytes.Equal(dbHash, i.hash) {
+	// A bootstrapped index keeps the bootstrap block after the AST hash.
+	if len(dbHash) < len(i.hash) || !bytes.Equal(dbHash[:len(i.hash)], i.hash) {
 		return fmt.Errorf("Invalid AST Hash: %x, expected: %x", dbHash, i.hash)
 	}
 	for {
-		var blockToFetch uint64
+		blockToFetch := i.startBlock
 		var lastBlockHash []byte
-		lastBlock, err := redis.Bytes(redisConn.Do("GET", "LAST_BLOCK"))
-		if err != nil && err != redis.ErrNil {
+		lastBlock, err := i.store.Get("LAST_BLOCK")
+		if err != nil {
+			return err
+		}
+		// bootstrapBlock is kept till bootstrapping is done, so it is
+		// resumed after a restart.
+		bootstrapBlock, err := i.store.Get(bootstrapBlockKey)
+		if err != nil {
 			return err
 		}
 		if len(lastBlock) == 40 {
 			lastBlockNumber := binary.LittleEndian.Uint64(lastBlock)
 			blockToFetch = lastBlockNumber + 1
 			lastBlockHash = lastBlock[8:]
+			if i.reorgDepth > 0 && !i.pruned {
+				err = i.pruneBlocks(lastBlockNumber)
+				if err != nil {
+					return err
+				}
+				i.pruned = true
+			}
+		} else if i.startBlock > 0 && i.bootstrapCells {
+			blockToFetch = 0
+			bootstrapBlock = make([]byte, 8)
+			binary.LittleEndian.PutUint64(bootstrapBlock, i.startBlock-1)
+		}
+		if len(bootstrapBlock) == 8 {
+			bootstrapped, err := i.bootstrap(blockToFetch, lastBlockHash, binary.LittleEndian.Uint64(bootstrapBlock))
+			if err != nil {
+				return err
+			}
+			if !bootstrapped {
+				return nil
+			}
+			continue
+		}
+
+		if i.bulkSync {
+			indexed, err := i.bulkIndex(blockToFetch, lastBlockHash)
+			if err != nil {
+				return err
+			}
+			if indexed {
+				continue
+			}
 		}
 
-		block, err := i.queryBlock(blockToFetch)
+		started := time.Now()
+		block, err := i.fetchBlock(blockToFetch)
 		if err != nil {
 			return err
 		}
 		if block == nil {
-			time.Sleep(time.Second)
-			continue
+			return nil
 		}
 
 		revert := lastBlockHash != nil && (!bytes.Equal(block.Header.ParentHash[:], lastBlockHash))
+		if revert && i.prefetcher != nil {
+			// Prefetched block might be stale, only revert when the fork is
+			// confirmed by a fresh fetch.
+			i.prefetcher.reset()
+			block, err = i.source.Block(blockToFetch)
+			if err != nil {
+				return i.sourceError(err)
+			}
+			if block == nil {
+				return nil
+			}
+			revert = !bytes.Equal(block.Header.ParentHash[:], lastBlockHash)
+		}
 		if revert {
 			if blockToFetch == 0 {
 				return fmt.Errorf("Nowhere to revert!")
@@ -139,88 +342,215 @@ func (i *Indexer) Run() error {
 			if err != nil {
 				return err
 			}
-			log.Printf("Reverted block number %d", blockToFetch-1)
+			i.progress.reverted(blockToFetch-1, time.Now())
+			if !i.reverting {
+				reorgs.WithLabelValues(i.name).Inc()
+				i.reverting = true
+			}
+			blocksReverted.WithLabelValues(i.name).Inc()
+			i.logf("Reverted block number %d", blockToFetch-1)
 			continue
 		}
 
-		// In the optimal path, we keep only one Redis connection
+		if i.storeCells {
+			err = i.resolveInputs(block, i.store.Get)
+			if err != nil {
+				return err
+			}
+		}
 		commands := &commandBuffer{}
 		err = i.indexBlock(*block, commands)
 		if err != nil {
 			return err
 		}
-		err = commands.execute(redisConn)
+		err = commands.execute(i.store)
 		if err != nil {
 			return err
 		}
-		log.Printf("Indexed block %x, block number %d", block.Header.Hash, block.Header.Number)
-	}
-}
-
-func (i *Indexer) queryBlock(blockNumber uint64) (*rpctypes.BlockView, error) {
-	req := graphql.NewRequest(`
-query($blockNumber: String) {
-  getBlock(number: $blockNumber) {
-    header {
-      parent_hash
-      hash
-      number
-    }
-    transactions {
-      hash
-      inputs {
-        previous_output {
-          cell {
-            capacity
-            lock {
-              code_hash
-              hash_type
-              args
-            }
-            type {
-              code_hash
-              hash_type
-              args
-            }
-          }
-          cell_data {
-            content
-          }
-          tx_hash
-          index
-        }
-      }
-      outputs {
-        capacity
-        lock {
-          code_hash
-          hash_type
-          args
-        }
-        type {
-          code_hash
-          hash_type
-          args
-        }
-      }
-      cells_data {
-        content
-      }
-    }
-  }
-}
-`)
-	req.Var("blockNumber", rpctypes.Uint64(blockNumber).EncodeToString())
-	var response getBlockResponse
-	err := i.graphqlClient.Run(context.Background(), req, &response)
+		i.progress.indexed(1, time.Now())
+		i.reverting = false
+		blocksIndexed.WithLabelValues(i.name).Inc()
+		blockDuration.WithLabelValues(i.name).Observe(time.Since(started).Seconds())
+		i.logf("Indexed block %x, block number %d", block.Header.Hash, block.Header.Number)
+	}
+}
+
+func (i *Indexer) resolveInputs(block *rpctypes.BlockView, get func(key string) ([]byte, error)) error {
+	fetch := func(outPoint rpctypes.OutPoint) (*source.StoredCell, error) {
+		cell, err := i.source.(source.StoredCellSource).PreviousOutput(outPoint)
+		return cell, i.sourceError(err)
+	}
+	return source.ResolveInputs(block, get, fetch)
+}
+
+// bulkIndex indexes a batch of final blocks starting from blockNumber in a
+// single commit, it returns false when the blocks should be indexed one by
+// one instead.
+func (i *Indexer) bulkIndex(blockNumber uint64, lastBlockHash []byte) (bool, error) {
+	if blockNumber+i.bulkDistance > i.bulkTip {
+		tip, err := i.source.(source.TipSource).TipBlockNumber()
+		if err != nil {
+			return false, i.sourceError(err)
+		}
+		i.bulkTip = tip
+		if blockNumber+i.bulkDistance > tip {
+			i.logf("Block number %d is close to tip %d, bulk sync is done", blockNumber, tip)
+			i.bulkSync = false
+			return false, nil
+		}
+	}
+	lastNumber := i.bulkTip - i.bulkDistance
+	if lastNumber-blockNumber >= i.bulkBatchSize {
+		lastNumber = blockNumber + i.bulkBatchSize - 1
+	}
+	commands := &commandBuffer{noRevert: true}
+	get := func(key string) ([]byte, error) {
+		return commands.get(i.store, key)
+	}
+	for number := blockNumber; number <= lastNumber; number++ {
+		block, err := i.fetchBlock(number)
+		if err != nil {
+			return false, err
+		}
+		if block == nil {
+			return false, fmt.Errorf("Block %d is missing while chain tip is %d!", number, i.bulkTip)
+		}
+		if lastBlockHash != nil && !bytes.Equal(block.Header.ParentHash[:], lastBlockHash) {
+			if number == blockNumber {
+				// Indexed blocks might be reverted, which is only possible
+				// when indexing blocks one by one.
+				return false, nil
+			}
+			return false, fmt.Errorf("Block %d does not connect to its parent, final blocks are reverted!", number)
+		}
+		if i.storeCells {
+			err = i.resolveInputs(block, get)
+			if err != nil {
+				return false, err
+			}
+		}
+		err = i.indexBlock(*block, commands)
+		if err != nil {
+			return false, err
+		}
+		lastBlockHash = block.Header.Hash[:]
+	}
+	err := commands.execute(i.store)
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	i.progress.indexed(lastNumber-blockNumber+1, time.Now())
+	blocksIndexed.WithLabelValues(i.name).Add(float64(lastNumber - blockNumber + 1))
+	i.logf("Bulk indexed block number %d to %d", blockNumber, lastNumber)
+	return true, nil
+}
+
+// bootstrap indexes blocks till bootstrapBlock, the block right before the
+// start block, so cells live before the start block are indexed. It returns
+// false when a block is not available yet. Like bulk sync, bootstrapped
+// blocks are indexed in batches without revert commands, they do not
+// publish stream values either. Each batch is committed together with
+// LAST_BLOCK and the bootstrap block, so an interrupted bootstrap resumes
+// from the last committed batch. Once done, the bootstrap block is recorded
+// after the AST hash instead.
+func (i *Indexer) bootstrap(blockNumber uint64, lastBlockHash []byte, bootstrapBlock uint64) (bool, error) {
+	lastNumber := bootstrapBlock
+	if lastNumber-blockNumber >= i.bootstrapBatchSize() {
+		lastNumber = blockNumber + i.bootstrapBatchSize() - 1
+	}
+	commands := &commandBuffer{noRevert: true, noStreams: true}
+	get := func(key string) ([]byte, error) {
+		return commands.get(i.store, key)
+	}
+	number := blockNumber
+	for ; number <= lastNumber; number++ {
+		block, err := i.fetchBlock(number)
+		if err != nil {
+			return false, err
+		}
+		if block == nil {
+			break
+		}
+		if lastBlockHash != nil && !bytes.Equal(block.Header.ParentHash[:], lastBlockHash) {
+			// Bootstrapped blocks cannot be reverted, the bootstrap has to
+			// start over.
+			err = i.clearBootstrap()
+			if err != nil {
+				return false, err
+			}
+			return false, fmt.Errorf("Block %d does not follow block %d, the chain is reorganized while bootstrapping!", number, number-1)
+		}
+		if i.storeCells {
+			err = i.resolveInputs(block, get)
+			if err != nil {
+				return false, err
+			}
+		}
+		err = i.indexBlock(*block, commands)
+		if err != nil {
+			return false, err
+		}
+		// Only the hash of the last bootstrapped block is kept.
+		if number > 0 {
+			commands.batch.Delete(blockHashKey(number - 1))
+		}
+		lastBlockHash = block.Header.Hash[:]
+	}
+	if number == blockNumber {
+		return false, nil
+	}
+	if number-1 == bootstrapBlock {
+		lastBlock := make([]byte, 40)
+		binary.LittleEndian.PutUint64(lastBlock, bootstrapBlock)
+		copy(lastBlock[8:], lastBlockHash)
+		commands.batch.Set("AST_HASH", append(append([]byte{}, i.hash...), lastBlock...))
+		commands.batch.Delete(bootstrapBlockKey)
+	} else {
+		value := make([]byte, 8)
+		binary.LittleEndian.PutUint64(value, bootstrapBlock)
+		commands.batch.Set(bootstrapBlockKey, value)
+	}
+	err := commands.execute(i.store)
+	if err != nil {
+		return false, err
+	}
+	i.progress.indexed(number-blockNumber, time.Now())
+	blocksIndexed.WithLabelValues(i.name).Add(float64(number - blockNumber))
+	i.logf("Bootstrapped block number %d to %d", blockNumber, number-1)
+	return number > lastNumber, nil
+}
+
+func (i *Indexer) bootstrapBatchSize() uint64 {
+	if i.bulkBatchSize > 0 {
+		return i.bulkBatchSize
+	}
+	return defaultBootstrapBatchSize
+}
+
+// clearBootstrap deletes all keys written by bootstrapping, except for the
+// AST hash. Index sets of skipped queries belong to another index.
+func (i *Indexer) clearBootstrap() error {
+	for _, query := range i.queries {
+		if i.skippedQueries[query.fingerprint] {
+			continue
+		}
+		err := i.store.DeletePrefix(QueryKeyPrefix(query.fingerprint))
+		if err != nil {
+			return err
+		}
+	}
+	for _, prefix := range []string{"CELL:", "BLOCK:", "LAST_BLOCK", bootstrapBlockKey} {
+		err := i.store.DeletePrefix(prefix)
+		if err != nil {
+			return err
+		}
 	}
-	return response.GetBlock, nil
+	return nil
 }
 
 func (i *Indexer) indexBlock(block rpctypes.BlockView, commands *commandBuffer) error {
 	var err error
+	startOps := len(commands.batch.Ops)
 	for _, tx := range block.Transactions {
 		for _, input := range tx.RawTransaction.Inputs {
 			if input.PreviousOutput.GraphqlCell != nil &&
@@ -233,74 +563,136 @@ func (i *Indexer) indexBlock(block rpctypes.BlockView, commands *commandBuffer)
 				if err != nil {
 					return err
 				}
+				if i.storeCells {
+					key := source.CellKey(input.PreviousOutput)
+					var value []byte
+					if !commands.noRevert {
+						value, err = commands.get(i.store, key)
+						if err != nil {
+							return err
+						}
+					}
+					commands.delete(key, value)
+				}
 			}
 		}
 
 		for outputIndex, output := range tx.RawTransaction.Outputs {
+			outPoint := rpctypes.OutPoint{
+				TxHash: tx.Hash,
+				Index:  rpctypes.Uint32(outputIndex),
+			}
 			err = i.processCell(output,
 				*tx.RawTransaction.GraphqlCellsData[outputIndex].Content,
-				rpctypes.OutPoint{
-					TxHash: tx.Hash,
-					Index:  rpctypes.Uint32(outputIndex),
-				},
+				outPoint,
 				true,
 				commands)
 			if err != nil {
 				return err
 			}
+			if i.storeCells {
+				key := source.CellKey(outPoint)
+				value, err := source.EncodeCell(source.StoredCell{
+					Output:    output,
+					Data:      *tx.RawTransaction.GraphqlCellsData[outputIndex].Content,
+					BlockHash: block.Header.Hash,
+				})
+				if err != nil {
+					return err
+				}
+				commands.set(key, value)
+			}
 		}
 	}
 	blockNumber := uint64(block.Header.Number)
-	blockHashKey := fmt.Sprintf("BLOCK:%d:HASH", blockNumber)
-	commands.do("SET", blockHashKey, block.Header.Hash[:])
+	hashKey := blockHashKey(blockNumber)
+	commands.batch.Set(hashKey, block.Header.Hash[:])
+	if i.reorgDepth > 0 && blockNumber >= i.reorgDepth {
+		// Pruned blocks stay pruned when this block is reverted.
+		commands.batch.Delete(blockHashKey(blockNumber - i.reorgDepth))
+		commands.batch.Delete(revertCommandsKey(blockNumber - i.reorgDepth))
+	}
 	lastBlock := make([]byte, 40)
 	binary.LittleEndian.PutUint64(lastBlock, blockNumber)
 	copy(lastBlock[8:], block.Header.Hash[:])
-	commands.do("SET", "LAST_BLOCK", lastBlock)
+	commands.batch.Set("LAST_BLOCK", lastBlock)
 
-	revertKey := fmt.Sprintf("BLOCK:%d:REVERT_COMMANDS", blockNumber)
+	revertKey := revertCommandsKey(blockNumber)
 	commands.setRevertKey(revertKey)
-	commands.revertDo("DEL", blockHashKey)
+	commands.revertDo(store.Op{Type: store.OpDelete, Key: hashKey})
 	if blockNumber > 0 {
 		previousBlock := make([]byte, 40)
 		binary.LittleEndian.PutUint64(previousBlock, blockNumber-1)
 		copy(previousBlock[8:], block.Header.ParentHash[:])
-		commands.revertDo("SET", "LAST_BLOCK", previousBlock)
+		commands.revertDo(store.Op{Type: store.OpSet, Key: "LAST_BLOCK", Value: previousBlock})
 	} else {
-		commands.revertDo("DEL", "LAST_BLOCK")
+		commands.revertDo(store.Op{Type: store.OpDelete, Key: "LAST_BLOCK"})
 	}
-	commands.revertDo("DEL", revertKey)
+	commands.revertDo(store.Op{Type: store.OpDelete, Key: revertKey})
 
+	blockCommands.WithLabelValues(i.name).Observe(float64(len(commands.batch.Ops) - startOps))
 	return nil
 }
 
 func (i *Indexer) revertBlock(blockNumber uint64) error {
-	conn := i.redisPool.Get()
-	defer conn.Close()
-
-	revertKey := fmt.Sprintf("BLOCK:%d:REVERT_COMMANDS", blockNumber)
-	revertData, err := redis.Bytes(conn.Do("GET", revertKey))
+	key := revertCommandsKey(blockNumber)
+	// Revert commands are missing for pruned blocks, as well as blocks that
+	// are bulk indexed or bootstrapped.
+	revertCommands, err := i.store.Get(key)
 	if err != nil {
 		return err
 	}
+	if revertCommands == nil {
+		return fmt.Errorf("Reorg reverting block %d is deeper than kept revert commands!", blockNumber)
+	}
+	return i.store.Revert(key)
+}
 
-	var revertCommands []command
-	gzipReader, err := gzip.NewReader(bytes.NewReader(revertData))
-	if err != nil {
-		return err
+// pruneBlocks deletes hashes and revert commands of blocks that are more
+// than reorgDepth blocks behind lastBlockNumber, which are kept by indexers
+// without a reorg depth.
+func (i *Indexer) pruneBlocks(lastBlockNumber uint64) error {
+	if lastBlockNumber < i.reorgDepth {
+		return nil
 	}
-	decoder := json.NewDecoder(gzipReader)
-	err = decoder.Decode(&revertCommands)
-	if err != nil {
-		return err
+	batch := &store.Batch{}
+	for number := lastBlockNumber - i.reorgDepth; ; number-- {
+		hash, err := i.store.Get(blockHashKey(number))
+		if err != nil {
+			return err
+		}
+		revertCommands, err := i.store.Get(revertCommandsKey(number))
+		if err != nil {
+			return err
+		}
+		if hash == nil && revertCommands == nil {
+			break
+		}
+		batch.Delete(blockHashKey(number))
+		batch.Delete(revertCommandsKey(number))
+		if len(batch.Ops) >= 1000 || number == 0 {
+			err = i.store.Commit(batch)
+			if err != nil {
+				return err
+			}
+			batch = &store.Batch{}
+		}
+		if number == 0 {
+			break
+		}
 	}
-
-	conn.Send("MULTI")
-	for _, command := range revertCommands {
-		conn.Send(command.Name, command.Args...)
+	if len(batch.Ops) > 0 {
+		return i.store.Commit(batch)
 	}
-	_, err = conn.Do("EXEC")
-	return err
+	return nil
+}
+
+func blockHashKey(blockNumber uint64) string {
+	return fmt.Sprintf("BLOCK:%d:HASH", blockNumber)
+}
+
+func revertCommandsKey(blockNumber uint64) string {
+	return fmt.Sprintf("BLOCK:%d:REVERT_COMMANDS", blockNumber)
 }
 
 func (i *Indexer) processCell(cell rpctypes.CellOutput, cellData rpctypes.Raw, outPoint rpctypes.OutPoint, insert bool, commands *commandBuffer) error {
@@ -312,22 +704,23 @@ func (i *Indexer) processCell(cell rpctypes.CellOutput, cellData rpctypes.Raw, o
 	// be a quite big change so we will leave it till a future time when it is
 	// really needed.
 	astCell := ast.ConvertCell(cell, cellData, outPoint, nil)
-	for _, valueContext := range i.values {
-		for queryIndex, query := range valueContext.Queries {
-			indexedValues, err := executeIndexingQuery(query, astCell)
+	for _, query := range i.queries {
+		if i.skippedQueries[query.fingerprint] {
+			continue
+		}
+		indexedValues, err := executeIndexingQuery(query.context.Queries[query.queryIndex], astCell)
+		if err != nil {
+			return err
+		}
+		if indexedValues != nil {
+			key, err := query.context.IndexKey(query.queryIndex, indexedValues)
 			if err != nil {
 				return err
 			}
-			if indexedValues != nil {
-				key, err := valueContext.IndexKey(queryIndex, indexedValues)
-				if err != nil {
-					return err
-				}
-				if insert {
-					commands.insert(key, outPoint)
-				} else {
-					commands.remove(key, outPoint)
-				}
+			if insert {
+				commands.insert(key, outPoint)
+			} else {
+				commands.remove(key, outPoint)
 			}
 		}
 	}
@@ -351,55 +744,47 @@ func (i *Indexer) processCell(cell rpctypes.CellOutput, cellData rpctypes.Raw, o
 	return nil
 }
 
-type command struct {
-	Name string        `json:"n"`
-	Args []interface{} `json:"a"`
-}
-
 type commandBuffer struct {
-	commands       []command
-	revertCommands []command
-	// Those are kept separated since they will be reversed.
-	streamRevertCommands []command
-	revertKey            string
-	err                  error
+	batch store.Batch
+	// Those are kept separated since they will be reversed, so a cell created
+	// and consumed in the same block is reverted correctly.
+	reversedRevertOps []store.Op
+	// values keeps values set or deleted (as nil) in current buffer, so
+	// blocks indexed later in the same buffer can see them.
+	values map[string][]byte
+	// noRevert skips all revert commands, it is used for final blocks.
+	noRevert bool
+	// noStreams skips publishing stream values.
+	noStreams bool
+	err       error
 }
 
-func (c *commandBuffer) do(commandName string, args ...interface{}) {
-	if c.err != nil {
-		return
+func (c *commandBuffer) get(s store.Store, key string) ([]byte, error) {
+	if value, found := c.values[key]; found {
+		return value, nil
 	}
-	c.commands = append(c.commands, command{
-		Name: commandName,
-		Args: args,
-	})
+	return s.Get(key)
 }
 
-func (c *commandBuffer) revertDo(commandName string, args ...interface{}) {
-	if c.err != nil {
-		return
+func (c *commandBuffer) setValue(key string, value []byte) {
+	if c.values == nil {
+		c.values = make(map[string][]byte)
 	}
-	c.revertCommands = append(c.revertCommands, command{
-		Name: commandName,
-		Args: args,
-	})
+	c.values[key] = value
 }
 
-func (c *commandBuffer) streamRevertDo(commandName string, args ...interface{}) {
-	if c.err != nil {
+func (c *commandBuffer) revertDo(op store.Op) {
+	if c.err != nil || c.noRevert {
 		return
 	}
-	c.streamRevertCommands = append(c.streamRevertCommands, command{
-		Name: commandName,
-		Args: args,
-	})
+	c.batch.RevertOps = append(c.batch.RevertOps, op)
 }
 
 func (c *commandBuffer) setRevertKey(key string) {
-	if c.err != nil {
+	if c.err != nil || c.noRevert {
 		return
 	}
-	c.revertKey = key
+	c.batch.RevertKey = key
 }
 
 func (c *commandBuffer) insert(key string, outPoint rpctypes.OutPoint) {
@@ -408,8 +793,11 @@ func (c *commandBuffer) insert(key string, outPoint rpctypes.OutPoint) {
 	}
 	var buffer bytes.Buffer
 	c.err = outPoint.SerializeToCore(&buffer)
-	c.do("SADD", key, buffer.Bytes())
-	c.revertDo("SREM", key, buffer.Bytes())
+	c.batch.Add(key, buffer.Bytes())
+	if c.noRevert {
+		return
+	}
+	c.reversedRevertOps = append(c.reversedRevertOps, store.Op{Type: store.OpRemove, Key: key, Value: buffer.Bytes()})
 }
 
 func (c *commandBuffer) remove(key string, outPoint rpctypes.OutPoint) {
@@ -418,58 +806,85 @@ func (c *commandBuffer) remove(key string, outPoint rpctypes.OutPoint) {
 	}
 	var buffer bytes.Buffer
 	c.err = outPoint.SerializeToCore(&buffer)
-	c.do("SREM", key, buffer.Bytes())
-	c.revertDo("SADD", key, buffer.Bytes())
+	c.batch.Remove(key, buffer.Bytes())
+	if c.noRevert {
+		return
+	}
+	c.reversedRevertOps = append(c.reversedRevertOps, store.Op{Type: store.OpAdd, Key: key, Value: buffer.Bytes()})
 }
 
-func (c *commandBuffer) streamValue(name string, value []byte) {
+// set stores value for a key that does not exist yet.
+func (c *commandBuffer) set(key string, value []byte) {
 	if c.err != nil {
 		return
 	}
-	key := fmt.Sprintf("STREAM:%s", name)
-	c.do("PUBLISH", key, value)
+	c.batch.Set(key, value)
+	c.setValue(key, value)
+	if c.noRevert {
+		return
+	}
+	c.reversedRevertOps = append(c.reversedRevertOps, store.Op{Type: store.OpDelete, Key: key})
 }
 
-func (c *commandBuffer) revertStreamValue(name string, value []byte) {
+// delete removes a key, oldValue is only required for reverting, and can be
+// nil when revert commands are skipped.
+func (c *commandBuffer) delete(key string, oldValue []byte) {
 	if c.err != nil {
 		return
 	}
-	key := fmt.Sprintf("STREAM:%s", name)
-	c.streamRevertDo("PUBLISH", key, value)
+	if oldValue == nil && !c.noRevert {
+		c.err = fmt.Errorf("Deleting missing key %s!", key)
+		return
+	}
+	c.batch.Delete(key)
+	c.setValue(key, nil)
+	if c.noRevert {
+		return
+	}
+	c.reversedRevertOps = append(c.reversedRevertOps, store.Op{Type: store.OpSet, Key: key, Value: oldValue})
 }
 
-func (c *commandBuffer) execute(conn redis.Conn) error {
+func (c *commandBuffer) streamValue(name string, value []byte) {
+	if c.err != nil || c.noStreams {
+		return
+	}
+	c.batch.Publish(StreamKey(name), value)
+}
+
+func (c *commandBuffer) revertStreamValue(name string, value []byte) {
+	if c.err != nil || c.noRevert {
+		return
+	}
+	c.reversedRevertOps = append(c.reversedRevertOps, store.Op{
+		Type:  store.OpPublish,
+		Key:   StreamKey(name),
+		Value: value,
+	})
+}
+
+func (c *commandBuffer) execute(s store.Store) error {
 	if c.err != nil {
 		return c.err
 	}
 
-	if len(c.revertKey) == 0 {
+	if len(c.batch.RevertKey) == 0 && !c.noRevert {
 		return fmt.Errorf("Revert key is missing!")
 	}
-	var buf bytes.Buffer
-	gzipWriter := gzip.NewWriter(&buf)
-	encoder := json.NewEncoder(gzipWriter)
-	revertCommands := make([]command, len(c.revertCommands)+len(c.streamRevertCommands))
-	copy(revertCommands, c.revertCommands)
-	for i, c := range c.streamRevertCommands {
-		revertCommands[len(revertCommands)-1-i] = c
-	}
-	err := encoder.Encode(c.revertCommands)
-	if err != nil {
-		return err
-	}
-	err = gzipWriter.Close()
-	if err != nil {
-		return err
+	for i := len(c.reversedRevertOps) - 1; i >= 0; i-- {
+		c.batch.RevertOps = append(c.batch.RevertOps, c.reversedRevertOps[i])
 	}
+	c.reversedRevertOps = nil
+	return s.Commit(&c.batch)
+}
 
-	conn.Send("MULTI")
-	for _, command := range c.commands {
-		conn.Send(command.Name, command.Args...)
-	}
-	conn.Send("SET", c.revertKey, buf.Bytes())
-	_, err = conn.Do("EXEC")
-	return err
+func StreamKey(name string) string {
+	return fmt.Sprintf("STREAM:%s", name)
+}
+
+// ProgramKeyPrefix is the prefix of all keys of a program, when multiple
+// programs share one store.
+func ProgramKeyPrefix(name string) string {
+	return fmt.Sprintf("PROGRAM:%s:", name)
 }
 
 type indexingEnvironment struct {
@@ -0,0 +1,532 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/xxuejie/animagus/pkg/ast"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+func arg(i uint64) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_ARG,
+		Primitive: &ast.Value_U{
+			U: i,
+		},
+	}
+}
+
+func param(i uint64) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_PARAM,
+		Primitive: &ast.Value_U{
+			U: i,
+		},
+	}
+}
+
+func bytes_value(b []byte) *ast.Value {
+	return &ast.Value{
+		T: ast.Value_BYTES,
+		Primitive: &ast.Value_Raw{
+			Raw: b,
+		},
+	}
+}
+
+// testCells queries all cells whose lock args equal to param 0
+func testCells() *ast.Value {
+	return &ast.Value{
+		T: ast.Value_QUERY_CELLS,
+		Children: []*ast.Value{
+			&ast.Value{
+				T: ast.Value_EQUAL,
+				Children: []*ast.Value{
+					&ast.Value{
+						T: ast.Value_GET_ARGS,
+						Children: []*ast.Value{
+							&ast.Value{
+								T:        ast.Value_GET_LOCK,
+								Children: []*ast.Value{arg(0)},
+							},
+						},
+					},
+					param(0),
+				},
+			},
+		},
+	}
+}
+
+func testAst(t *testing.T) []byte {
+	root := &ast.Root{
+		Calls: []*ast.Call{
+			&ast.Call{
+				Name:   "cells",
+				Result: testCells(),
+			},
+		},
+		Streams: []*ast.Stream{
+			&ast.Stream{
+				Name: "changes",
+				Filter: &ast.Value{
+					T: ast.Value_LIST,
+					Children: []*ast.Value{
+						arg(1),
+						arg(2),
+						&ast.Value{
+							T:        ast.Value_GET_CAPACITY,
+							Children: []*ast.Value{arg(0)},
+						},
+					},
+				},
+			},
+		},
+	}
+	content, err := proto.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+type testOutput struct {
+	capacity uint64
+	args     byte
+}
+
+func testTx(inputs []rpctypes.OutPoint, outputs ...testOutput) rpctypes.Transaction {
+	tx := rpctypes.Transaction{}
+	for _, input := range inputs {
+		tx.Inputs = append(tx.Inputs, rpctypes.CellInput{PreviousOutput: input})
+	}
+	for _, output := range outputs {
+		tx.Outputs = append(tx.Outputs, rpctypes.CellOutput{
+			Capacity: rpctypes.Uint64(output.capacity),
+			Lock: rpctypes.Script{
+				HashType: rpctypes.Data,
+				Args:     rpctypes.Bytes{output.args},
+			},
+		})
+		tx.OutputsData = append(tx.OutputsData, rpctypes.Bytes{})
+	}
+	return tx
+}
+
+func outPoint(block *rpctypes.BlockView, txIndex int, index uint32) rpctypes.OutPoint {
+	return rpctypes.OutPoint{
+		TxHash: block.Transactions[txIndex].Hash,
+		Index:  rpctypes.Uint32(index),
+	}
+}
+
+func serializeOutPoint(t *testing.T, o rpctypes.OutPoint) []byte {
+	var buffer bytes.Buffer
+	err := o.SerializeToCore(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func newTestIndexer(t *testing.T, s store.Store, src source.Source) *Indexer {
+	i, err := NewIndexer(testAst(t), s, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func assertCells(t *testing.T, i *Indexer, s store.Store, args byte, expected ...rpctypes.OutPoint) {
+	key, err := i.values[0].IndexKey(0, map[int]*ast.Value{0: bytes_value([]byte{args})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := s.Members(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != len(expected) {
+		t.Fatalf("Invalid number of cells for args %x: %d, expected: %d", args, len(members), len(expected))
+	}
+	for _, e := range expected {
+		found := false
+		for _, member := range members {
+			if bytes.Equal(member, serializeOutPoint(t, e)) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Cell %x is not indexed for args %x", serializeOutPoint(t, e), args)
+		}
+	}
+}
+
+func TestIndexBlocks(t *testing.T) {
+	src := source.NewMemorySource()
+	block0, err := src.AppendTransactions(
+		testTx(nil, testOutput{100, 1}, testOutput{200, 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block1, err := src.AppendTransactions(
+		testTx([]rpctypes.OutPoint{outPoint(block0, 0, 0)}, testOutput{50, 2}, testOutput{50, 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, src)
+
+	subscription, err := s.Subscribe(StreamKey("changes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Close()
+
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCells(t, i, s, 1)
+	assertCells(t, i, s, 2, outPoint(block0, 0, 1), outPoint(block1, 0, 0))
+	assertCells(t, i, s, 3, outPoint(block1, 0, 1))
+
+	lastBlock, err := s.Get("LAST_BLOCK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(lastBlock[8:], block1.Header.Hash[:]) {
+		t.Errorf("Invalid last block: %x", lastBlock)
+	}
+
+	expected := []string{"insert:100", "insert:200", "remove:100", "insert:50", "insert:50"}
+	for _, e := range expected {
+		data, err := subscription.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		value := &ast.Value{}
+		err = proto.Unmarshal(data, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		children := value.GetChildren()
+		if string(children[1].GetRaw()) != "index" {
+			t.Errorf("Invalid stream value: %s", proto.CompactTextString(value))
+		}
+		actual := fmt.Sprintf("%s:%d", children[0].GetRaw(), children[2].GetU())
+		if actual != e {
+			t.Errorf("Invalid stream value: %s, expected: %s", actual, e)
+		}
+	}
+}
+
+func TestRevertBlocks(t *testing.T) {
+	src := source.NewMemorySource()
+	block0, err := src.AppendTransactions(
+		testTx(nil, testOutput{100, 1}, testOutput{200, 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block1, err := src.AppendTransactions(
+		testTx([]rpctypes.OutPoint{outPoint(block0, 0, 0)}, testOutput{50, 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cell created and consumed in the same block
+	tx := testTx([]rpctypes.OutPoint{outPoint(block1, 0, 0)}, testOutput{40, 3})
+	txHash, err := rpctypes.CalculateHash(tx.RawTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spent := rpctypes.OutPoint{Index: 0}
+	copy(spent.TxHash[:], txHash)
+	_, err = src.AppendTransactions(tx, testTx([]rpctypes.OutPoint{spent}, testOutput{30, 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, src)
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCells(t, i, s, 2, outPoint(block0, 0, 1))
+
+	// Fork from block 1 with a longer chain
+	src.Rollback(1)
+	forkBlock1, err := src.AppendTransactions(
+		testTx([]rpctypes.OutPoint{outPoint(block0, 0, 1)}, testOutput{150, 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.AppendTransactions(testTx(nil, testOutput{10, 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.AppendTransactions(testTx(nil, testOutput{20, 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCells(t, i, s, 1, outPoint(block0, 0, 0))
+	assertCells(t, i, s, 2)
+	assertCells(t, i, s, 3, outPoint(forkBlock1, 0, 0))
+
+	// Reverted store should contain the same data as a store indexing the
+	// fork directly.
+	freshStore := store.NewMemoryStore()
+	err = newTestIndexer(t, freshStore, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameStore(t, s, freshStore, "")
+}
+
+// assertSameStore compares keys and values of 2 stores, keys starting with
+// skipPrefix are ignored when skipPrefix is not empty.
+func assertSameStore(t *testing.T, s *store.MemoryStore, expected *store.MemoryStore, skipPrefix string) {
+	filter := func(keys []string) []string {
+		var results []string
+		for _, key := range keys {
+			if len(skipPrefix) == 0 || !strings.HasPrefix(key, skipPrefix) {
+				results = append(results, key)
+			}
+		}
+		return results
+	}
+	keys := filter(s.Keys())
+	expectedKeys := filter(expected.Keys())
+	if len(keys) != len(expectedKeys) {
+		t.Fatalf("Invalid keys: %v, expected: %v", keys, expectedKeys)
+	}
+	for j, key := range keys {
+		if key != expectedKeys[j] {
+			t.Fatalf("Invalid keys: %v, expected: %v", keys, expectedKeys)
+		}
+		if strings.HasSuffix(key, ":REVERT_COMMANDS") {
+			continue
+		}
+		value, _ := s.Get(key)
+		expectedValue, _ := expected.Get(key)
+		members, _ := s.Members(key)
+		expectedMembers, _ := expected.Members(key)
+		if !bytes.Equal(value, expectedValue) || len(members) != len(expectedMembers) {
+			t.Errorf("Key %s has different values", key)
+		}
+	}
+}
+
+func newTestRpcServer(t *testing.T, src *source.MemorySource) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Id     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		response := map[string]interface{}{
+			"id":      request.Id,
+			"jsonrpc": "2.0",
+		}
+		switch request.Method {
+		case "get_tip_block_number":
+			response["result"] = src.Tip().Header.Number
+		case "get_block_by_number":
+			var number rpctypes.Uint64
+			err = json.Unmarshal(request.Params[0], &number)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			block, _ := src.Block(uint64(number))
+			if block != nil {
+				// CKB returns neither cells data nor previous outputs
+				txs := make([]rpctypes.TransactionView, len(block.Transactions))
+				for i, tx := range block.Transactions {
+					tx.Inputs = append([]rpctypes.CellInput{}, tx.Inputs...)
+					for j := range tx.Inputs {
+						tx.Inputs[j].PreviousOutput = rpctypes.OutPoint{
+							TxHash: tx.Inputs[j].PreviousOutput.TxHash,
+							Index:  tx.Inputs[j].PreviousOutput.Index,
+						}
+					}
+					tx.GraphqlCellsData = nil
+					txs[i] = tx
+				}
+				block.Transactions = txs
+			}
+			response["result"] = block
+		default:
+			t.Errorf("Unexpected method: %s", request.Method)
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestRpcSource(t *testing.T) {
+	src := source.NewMemorySource()
+	block0, err := src.AppendTransactions(
+		testTx(nil, testOutput{100, 1}, testOutput{200, 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := testTx([]rpctypes.OutPoint{outPoint(block0, 0, 0)}, testOutput{40, 3})
+	txHash, err := rpctypes.CalculateHash(tx.RawTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spent := rpctypes.OutPoint{Index: 0}
+	copy(spent.TxHash[:], txHash)
+	_, err = src.AppendTransactions(tx, testTx([]rpctypes.OutPoint{spent}, testOutput{30, 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestRpcServer(t, src)
+	defer server.Close()
+
+	s := store.NewMemoryStore()
+	rpcSource, err := source.NewRpcSource(server.URL, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := newTestIndexer(t, s, rpcSource)
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameStore(t, s, expected, "CELL:")
+	for _, o := range []rpctypes.OutPoint{outPoint(block0, 0, 0), spent} {
+		value, _ := s.Get(source.CellKey(o))
+		if value != nil {
+			t.Errorf("Spent cell %x is kept!", serializeOutPoint(t, o))
+		}
+	}
+
+	// Reverting blocks also reverts stored cells
+	src.Rollback(1)
+	_, err = src.AppendTransactions(testTx([]rpctypes.OutPoint{outPoint(block0, 0, 1)}, testOutput{150, 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.AppendTransactions(testTx(nil, testOutput{10, 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := store.NewMemoryStore()
+	freshSource, err := source.NewRpcSource(server.URL, fresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = newTestIndexer(t, fresh, freshSource).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameStore(t, s, fresh, "")
+	value, _ := s.Get(source.CellKey(outPoint(block0, 0, 0)))
+	if value == nil {
+		t.Error("Reverted spent cell is not restored!")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	chain := source.NewMemorySource()
+	var documents []*rpctypes.BlockView
+	appendBlock := func(txs ...rpctypes.Transaction) *rpctypes.BlockView {
+		block, err := chain.AppendTransactions(txs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		documents = append(documents, block)
+		return block
+	}
+	block0 := appendBlock(testTx(nil, testOutput{100, 1}, testOutput{200, 2}))
+	block1 := appendBlock(testTx([]rpctypes.OutPoint{outPoint(block0, 0, 0)}, testOutput{50, 2}))
+	appendBlock(testTx(nil, testOutput{10, 3}))
+	chain.Rollback(2)
+	forkBlock2 := appendBlock(testTx([]rpctypes.OutPoint{outPoint(block0, 0, 1)}, testOutput{20, 4}))
+	forkBlock3 := appendBlock(testTx(nil, testOutput{5, 4}))
+
+	dir, err := ioutil.TempDir("", "animagus-blocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "blocks.jsonl")
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	// Replay starts from block 1
+	for _, document := range documents[1:] {
+		err = encoder.Encode(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = ioutil.WriteFile(path, buffer.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileSource, err := source.NewFileSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, fileSource)
+	i.SetStartBlock(fileSource.FirstBlockNumber())
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCells(t, i, s, 1)
+	assertCells(t, i, s, 2, outPoint(block1, 0, 0))
+	assertCells(t, i, s, 3)
+	assertCells(t, i, s, 4, outPoint(forkBlock2, 0, 0), outPoint(forkBlock3, 0, 0))
+	value, _ := s.Get("BLOCK:0:HASH")
+	if value != nil {
+		t.Error("Block 0 should not be indexed!")
+	}
+}
+
+func TestInvalidAstHash(t *testing.T) {
+	s := store.NewMemoryStore()
+	batch := &store.Batch{}
+	batch.Set("AST_HASH", []byte{1, 2, 3})
+	err := s.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = newTestIndexer(t, s, source.NewMemorySource()).Sync()
+	if err == nil {
+		t.Fatal("Indexer should reject a different AST hash!")
+	}
+}
@@ -0,0 +1,57 @@
+package indexer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics are labelled by program name, which is empty for an indexer
+// without a name.
+var (
+	blocksIndexed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "animagus",
+		Subsystem: "indexer",
+		Name:      "blocks_indexed_total",
+		Help:      "Number of blocks indexed, including bulk indexed blocks.",
+	}, []string{"program"})
+	reorgs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "animagus",
+		Subsystem: "indexer",
+		Name:      "reorgs_total",
+		Help:      "Number of reorgs detected.",
+	}, []string{"program"})
+	blocksReverted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "animagus",
+		Subsystem: "indexer",
+		Name:      "blocks_reverted_total",
+		Help:      "Number of blocks reverted by reorgs.",
+	}, []string{"program"})
+	blockDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "animagus",
+		Subsystem: "indexer",
+		Name:      "block_duration_seconds",
+		Help:      "Time from fetching a block to committing it, for blocks indexed one by one.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"program"})
+	blockCommands = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "animagus",
+		Subsystem: "indexer",
+		Name:      "commands_per_block",
+		Help:      "Number of store commands generated by indexing a block.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+	}, []string{"program"})
+	sourceErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "animagus",
+		Subsystem: "indexer",
+		Name:      "source_errors_total",
+		Help:      "Number of errors returned by the block source.",
+	}, []string{"program"})
+)
+
+// sourceError counts err if it is returned by the source.
+func (i *Indexer) sourceError(err error) error {
+	if err != nil {
+		sourceErrors.WithLabelValues(i.name).Inc()
+	}
+	return err
+}
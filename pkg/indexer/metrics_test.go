@@ -0,0 +1,68 @@
+package indexer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+type failingSource struct {
+	*source.MemorySource
+	fail bool
+}
+
+func (s *failingSource) Block(blockNumber uint64) (*rpctypes.BlockView, error) {
+	if s.fail {
+		return nil, fmt.Errorf("Source is down!")
+	}
+	return s.MemorySource.Block(blockNumber)
+}
+
+func TestMetrics(t *testing.T) {
+	src := testChain(t, 5)
+	failing := &failingSource{MemorySource: src}
+	i := newTestIndexer(t, store.NewMemoryStore(), failing)
+	i.SetName("metrics")
+	err := i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.ToFloat64(blocksIndexed.WithLabelValues("metrics")); n != 5 {
+		t.Errorf("Invalid number of indexed blocks: %f", n)
+	}
+
+	// One reorg reverting 2 blocks
+	src.Rollback(3)
+	for j := 0; j < 3; j++ {
+		_, err = src.AppendTransactions(testTx(nil, testOutput{uint64(j), 5}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.ToFloat64(reorgs.WithLabelValues("metrics")); n != 1 {
+		t.Errorf("Invalid number of reorgs: %f", n)
+	}
+	if n := testutil.ToFloat64(blocksReverted.WithLabelValues("metrics")); n != 2 {
+		t.Errorf("Invalid number of reverted blocks: %f", n)
+	}
+	if n := testutil.ToFloat64(blocksIndexed.WithLabelValues("metrics")); n != 8 {
+		t.Errorf("Invalid number of indexed blocks: %f", n)
+	}
+
+	failing.fail = true
+	err = i.Sync()
+	if err == nil {
+		t.Fatal("Source error is not returned!")
+	}
+	if n := testutil.ToFloat64(sourceErrors.WithLabelValues("metrics")); n != 1 {
+		t.Errorf("Invalid number of source errors: %f", n)
+	}
+}
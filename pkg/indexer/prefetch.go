@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/source"
+)
+
+type fetchResult struct {
+	block *rpctypes.BlockView
+	err   error
+}
+
+// prefetcher fetches blocks following the requested one concurrently, so
+// they are ready once the indexer gets to them. Prefetched blocks might be
+// stale after a reorg, the indexer calls reset and fetches again before
+// reverting anything.
+type prefetcher struct {
+	source  source.Source
+	depth   uint64
+	window  uint64
+	pending map[uint64]chan fetchResult
+}
+
+func newPrefetcher(s source.Source, depth uint64) *prefetcher {
+	return &prefetcher{
+		source:  s,
+		depth:   depth,
+		window:  1,
+		pending: make(map[uint64]chan fetchResult),
+	}
+}
+
+func (p *prefetcher) fetch(blockNumber uint64) (*rpctypes.BlockView, error) {
+	for number := range p.pending {
+		if number < blockNumber {
+			delete(p.pending, number)
+		}
+	}
+	for number := blockNumber; number < blockNumber+p.window; number++ {
+		if _, found := p.pending[number]; !found {
+			p.start(number)
+		}
+	}
+	result := <-p.pending[blockNumber]
+	delete(p.pending, blockNumber)
+	if result.err != nil || result.block == nil {
+		// Close to chain tip, there is no point fetching ahead.
+		p.reset()
+		p.window = 1
+	} else if p.window < p.depth {
+		p.window *= 2
+		if p.window > p.depth {
+			p.window = p.depth
+		}
+	}
+	return result.block, result.err
+}
+
+func (p *prefetcher) start(blockNumber uint64) {
+	c := make(chan fetchResult, 1)
+	p.pending[blockNumber] = c
+	go func() {
+		block, err := p.source.Block(blockNumber)
+		c <- fetchResult{block: block, err: err}
+	}()
+}
+
+// reset drops all pending blocks, fetching goroutines will still finish in
+// the background.
+func (p *prefetcher) reset() {
+	p.pending = make(map[uint64]chan fetchResult)
+}
@@ -0,0 +1,160 @@
+package indexer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/xxuejie/animagus/pkg/ast"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+// slowSource delays each fetch, and records the maximum number of concurrent
+// fetches. Blocks in stale are returned instead for their first fetch.
+type slowSource struct {
+	*source.MemorySource
+	mutex         sync.Mutex
+	running       int
+	maxConcurrent int
+	stale         map[uint64]*rpctypes.BlockView
+}
+
+func (s *slowSource) Block(blockNumber uint64) (*rpctypes.BlockView, error) {
+	s.mutex.Lock()
+	s.running++
+	if s.running > s.maxConcurrent {
+		s.maxConcurrent = s.running
+	}
+	stale := s.stale[blockNumber]
+	delete(s.stale, blockNumber)
+	s.mutex.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	s.mutex.Lock()
+	s.running--
+	s.mutex.Unlock()
+	if stale != nil {
+		return stale, nil
+	}
+	return s.MemorySource.Block(blockNumber)
+}
+
+func testChain(t *testing.T, n int) *source.MemorySource {
+	src := source.NewMemorySource()
+	block, err := src.AppendTransactions(testTx(nil, testOutput{1000, 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := 1; j < n; j++ {
+		// Keep moving part of the capacity from one lock to another
+		block, err = src.AppendTransactions(testTx([]rpctypes.OutPoint{outPoint(block, 0, 0)},
+			testOutput{uint64(1000 - j), byte(j%3 + 1)}, testOutput{1, 4}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return src
+}
+
+func TestPrefetchSync(t *testing.T) {
+	src := &slowSource{MemorySource: testChain(t, 20)}
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, src)
+	i.SetPrefetch(8)
+	err := i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.maxConcurrent < 2 {
+		t.Errorf("Blocks are not fetched concurrently!")
+	}
+
+	expected := store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src.MemorySource).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameStore(t, s, expected, "")
+}
+
+func TestPrefetchReorg(t *testing.T) {
+	src := &slowSource{MemorySource: testChain(t, 10)}
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, src)
+	i.SetPrefetch(4)
+	err := i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src.Rollback(6)
+	for j := 0; j < 8; j++ {
+		_, err = src.AppendTransactions(testTx(nil, testOutput{uint64(j), 5}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src.MemorySource).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameStore(t, s, expected, "")
+}
+
+func TestPrefetchStaleBlock(t *testing.T) {
+	src := &slowSource{MemorySource: testChain(t, 4)}
+	// A block from an abandoned fork, which does not connect to the main
+	// chain.
+	fork := testChain(t, 2)
+	_, err := fork.AppendTransactions(testTx(nil, testOutput{7, 7}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fork.AppendTransactions(testTx(nil, testOutput{8, 8}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	staleBlock, err := fork.Block(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.stale = map[uint64]*rpctypes.BlockView{3: staleBlock}
+
+	s := store.NewMemoryStore()
+	subscription, err := s.Subscribe(StreamKey("changes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Close()
+	i := newTestIndexer(t, s, src)
+	i.SetPrefetch(4)
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	for {
+		data, err := subscription.Receive()
+		if err != nil {
+			break
+		}
+		value := &ast.Value{}
+		err = proto.Unmarshal(data, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value.GetChildren()[1].GetRaw()) != "index" {
+			t.Fatalf("Stale block should not trigger revert: %s", proto.CompactTextString(value))
+		}
+	}
+	assertCells(t, i, s, 7)
+}
@@ -0,0 +1,452 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+const (
+	activeIndexKey = "ACTIVE_INDEX"
+	// indexesKey is the set of hashes of all indexes kept in the store.
+	indexesKey = "INDEXES"
+	// queriesKey is the set of fingerprints of all queries kept in the
+	// store.
+	queriesKey = "QUERIES"
+	astKey     = "AST"
+)
+
+// IndexKeyPrefix is the prefix of all keys of the index built for an AST
+// hash, within the keys of a program.
+func IndexKeyPrefix(hash []byte) string {
+	return fmt.Sprintf("INDEX:%x:", hash)
+}
+
+// legacyKeyPrefixes cover keys of an index written directly into the
+// keyspace of a store, by versions predating programs and index keyspaces.
+// AST_HASH goes last, so an interrupted deletion is retried.
+var legacyKeyPrefixes = []string{"CALL:", "BLOCK:", "CELL:", "LAST_BLOCK", "AST_HASH"}
+
+// DeleteLegacyKeys deletes an index written directly into s by an older
+// version, it returns false when there is none. Such an index uses a key
+// layout that cannot be served any more, programs index the chain again in
+// their own keyspaces instead.
+func DeleteLegacyKeys(s store.Store) (bool, error) {
+	hash, err := s.Get("AST_HASH")
+	if err != nil || hash == nil {
+		return false, err
+	}
+	for _, prefix := range legacyKeyPrefixes {
+		err = s.DeletePrefix(prefix)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// SourceFactory creates the source used by an index, s is the store of the
+// index.
+type SourceFactory func(s store.Store) (source.Source, error)
+
+// SwitchFunc is called after a program switches to a new index.
+type SwitchFunc func(astContent []byte, s store.Store, src source.Source) error
+
+type programIndex struct {
+	hash       []byte
+	astContent []byte
+	store      store.Store
+	source     source.Source
+	indexer    *Indexer
+}
+
+// Program keeps the index of an AST program up to date. Each AST hash is
+// indexed in its own keyspace, and ACTIVE_INDEX points to the one being
+// served. When the AST changes, the new AST is indexed from scratch in a
+// shadow keyspace, while the active index keeps being updated and served.
+// Once the shadow index catches up with the chain, the program switches to
+// it, and deletes keys of the old index.
+//
+// Index sets of queries are kept out of index keyspaces, under the
+// fingerprint of each query. The shadow index only indexes queries that are
+// not in the active index, index sets of other queries are reused. It still
+// reads all blocks from the block source.
+type Program struct {
+	name      string
+	store     store.Store
+	newSource SourceFactory
+	configure func(i *Indexer, src source.Source)
+	onSwitch  SwitchFunc
+	// mutex guards active, which is read by Active and Progress from other
+	// goroutines.
+	mutex  sync.Mutex
+	active *programIndex
+	shadow *programIndex
+}
+
+// NewProgram loads the indexes of a program from s, configure is called for
+// each created indexer and can be nil.
+func NewProgram(name string, astContent []byte, s store.Store, newSource SourceFactory, configure func(i *Indexer, src source.Source)) (*Program, error) {
+	hash, err := AstHash(astContent)
+	if err != nil {
+		return nil, err
+	}
+	p := &Program{
+		name:      name,
+		store:     s,
+		newSource: newSource,
+		configure: configure,
+	}
+	target, err := p.openIndex(hash, astContent)
+	if err != nil {
+		return nil, err
+	}
+	err = p.register(target)
+	if err != nil {
+		return nil, err
+	}
+	activeHash, err := s.Get(activeIndexKey)
+	if err != nil {
+		return nil, err
+	}
+	if activeHash == nil || bytes.Equal(activeHash, hash) {
+		err = p.activate(target)
+		if err != nil {
+			return nil, err
+		}
+		p.active = target
+	} else {
+		oldContent, err := s.Get(IndexKeyPrefix(activeHash) + astKey)
+		if err != nil {
+			return nil, err
+		}
+		var oldHash []byte
+		if oldContent != nil {
+			oldHash, err = AstHash(oldContent)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !bytes.Equal(oldHash, activeHash) {
+			// The active index is built by a different indexer version, it
+			// cannot be updated any more.
+			log.Printf("Program %s: index %x is not compatible, switching to index %x", name, activeHash, hash)
+			err = p.activate(target)
+			if err != nil {
+				return nil, err
+			}
+			p.active = target
+		} else {
+			p.active, err = p.openIndex(activeHash, oldContent)
+			if err != nil {
+				return nil, err
+			}
+			p.shadow = target
+			p.shadow.indexer.SkipQueries(p.active.indexer.Fingerprints())
+			log.Printf("Program %s: building index %x, still serving index %x", name, hash, activeHash)
+		}
+	}
+	err = p.collectGarbage()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// OnSwitch sets the function called after switching to a new index.
+func (p *Program) OnSwitch(f SwitchFunc) {
+	p.onSwitch = f
+}
+
+// Active returns the AST, store and source of the index being served.
+func (p *Program) Active() ([]byte, store.Store, source.Source) {
+	p.mutex.Lock()
+	active := p.active
+	p.mutex.Unlock()
+	return active.astContent, active.store, active.source
+}
+
+// Progress returns indexing progress of the active index.
+func (p *Program) Progress() Progress {
+	p.mutex.Lock()
+	active := p.active
+	p.mutex.Unlock()
+	return active.indexer.Progress()
+}
+
+func (p *Program) openIndex(hash []byte, astContent []byte) (*programIndex, error) {
+	s := store.NewPrefixStore(p.store, IndexKeyPrefix(hash), queryKeysPrefix)
+	src, err := p.newSource(s)
+	if err != nil {
+		return nil, err
+	}
+	i, err := NewIndexer(astContent, s, src)
+	if err != nil {
+		return nil, err
+	}
+	i.SetName(p.name)
+	if p.configure != nil {
+		p.configure(i, src)
+	}
+	return &programIndex{
+		hash:       hash,
+		astContent: astContent,
+		store:      s,
+		source:     src,
+		indexer:    i,
+	}, nil
+}
+
+// register keeps the AST of an index, so the index can still be served
+// after the AST file changes.
+func (p *Program) register(index *programIndex) error {
+	batch := &store.Batch{}
+	batch.Set(IndexKeyPrefix(index.hash)+astKey, index.astContent)
+	batch.Add(indexesKey, index.hash)
+	for _, fingerprint := range index.indexer.Fingerprints() {
+		batch.Add(queriesKey, []byte(fingerprint))
+	}
+	return p.store.Commit(batch)
+}
+
+func (p *Program) activate(index *programIndex) error {
+	batch := &store.Batch{}
+	batch.Set(activeIndexKey, index.hash)
+	return p.store.Commit(batch)
+}
+
+// collectGarbage deletes all indexes that are neither active nor being
+// built, such as a shadow index of an AST that changed again, as well as
+// an index written directly into the program keyspace by older versions.
+func (p *Program) collectGarbage() error {
+	deleted, err := DeleteLegacyKeys(p.store)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		log.Printf("Program %s: deleted index of a previous animagus version", p.name)
+	}
+	hashes, err := p.store.Members(indexesKey)
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if bytes.Equal(hash, p.active.hash) ||
+			(p.shadow != nil && bytes.Equal(hash, p.shadow.hash)) {
+			continue
+		}
+		err = p.store.DeletePrefix(IndexKeyPrefix(hash))
+		if err != nil {
+			return err
+		}
+		batch := &store.Batch{}
+		batch.Remove(indexesKey, hash)
+		err = p.store.Commit(batch)
+		if err != nil {
+			return err
+		}
+		log.Printf("Program %s: deleted index %x", p.name, hash)
+	}
+
+	used := make(map[string]bool)
+	for _, fingerprint := range p.active.indexer.Fingerprints() {
+		used[fingerprint] = true
+	}
+	if p.shadow != nil {
+		for _, fingerprint := range p.shadow.indexer.Fingerprints() {
+			used[fingerprint] = true
+		}
+	}
+	fingerprints, err := p.store.Members(queriesKey)
+	if err != nil {
+		return err
+	}
+	for _, fingerprint := range fingerprints {
+		if used[string(fingerprint)] {
+			continue
+		}
+		err = p.store.DeletePrefix(QueryKeyPrefix(string(fingerprint)))
+		if err != nil {
+			return err
+		}
+		batch := &store.Batch{}
+		batch.Remove(queriesKey, fingerprint)
+		err = p.store.Commit(batch)
+		if err != nil {
+			return err
+		}
+		log.Printf("Program %s: deleted query %s", p.name, fingerprint)
+	}
+	return nil
+}
+
+// trySwitch brings the shadow index to the same block as the active index,
+// then switches to it. It returns false if the indexes are not at the same
+// block yet, which might happen when new blocks arrive in the meantime.
+func (p *Program) trySwitch() (bool, error) {
+	err := p.shadow.indexer.Sync()
+	if err != nil {
+		return false, err
+	}
+	lastBlock, err := p.active.store.Get("LAST_BLOCK")
+	if err != nil {
+		return false, err
+	}
+	shadowLastBlock, err := p.shadow.store.Get("LAST_BLOCK")
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(lastBlock, shadowLastBlock) {
+		return false, nil
+	}
+	return true, p.switchIndex()
+}
+
+func (p *Program) switchIndex() error {
+	old := p.active
+	err := p.mergeRevertCommands(old, p.shadow)
+	if err != nil {
+		return err
+	}
+	err = p.activate(p.shadow)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	p.active, p.shadow = p.shadow, nil
+	p.mutex.Unlock()
+	p.active.indexer.SkipQueries(nil)
+	log.Printf("Program %s: switched from index %x to index %x", p.name, old.hash, p.active.hash)
+	if p.onSwitch != nil {
+		err = p.onSwitch(p.active.astContent, p.active.store, p.active.source)
+		if err != nil {
+			return err
+		}
+	}
+	return p.collectGarbage()
+}
+
+// mergeRevertCommands adds revert ops of reused queries from the old index
+// to revert commands of the new index, so blocks indexed before the switch
+// can still be reverted. Both indexes must be at the same block.
+func (p *Program) mergeRevertCommands(old *programIndex, index *programIndex) error {
+	lastBlock, err := index.store.Get("LAST_BLOCK")
+	if err != nil {
+		return err
+	}
+	if len(lastBlock) != 40 {
+		return nil
+	}
+	// Queries skipped by the new index are the reused ones.
+	reused := index.indexer.skippedQueries
+	batch := &store.Batch{}
+	for number := binary.LittleEndian.Uint64(lastBlock); ; number-- {
+		key := revertCommandsKey(number)
+		data, err := index.store.Get(key)
+		if err != nil {
+			return err
+		}
+		oldData, err := old.store.Get(key)
+		if err != nil {
+			return err
+		}
+		if data == nil || oldData == nil {
+			break
+		}
+		ops, err := store.DecodeOps(data)
+		if err != nil {
+			return err
+		}
+		oldOps, err := store.DecodeOps(oldData)
+		if err != nil {
+			return err
+		}
+		var merged []store.Op
+		for _, op := range oldOps {
+			if reused[queryFingerprintOfKey(op.Key)] {
+				merged = append(merged, op)
+			}
+		}
+		data, err = store.EncodeOps(append(merged, ops...))
+		if err != nil {
+			return err
+		}
+		batch.Set(key, data)
+		if number == 0 {
+			break
+		}
+	}
+	return index.store.Commit(batch)
+}
+
+// queryFingerprintOfKey extracts the query fingerprint from a key stored
+// in revert commands, which might contain prefixes of outer stores.
+func queryFingerprintOfKey(key string) string {
+	index := strings.LastIndex(key, queryKeysPrefix)
+	if index == -1 {
+		return ""
+	}
+	key = key[index+len(queryKeysPrefix):]
+	index = strings.Index(key, ":")
+	if index == -1 {
+		return ""
+	}
+	return key[:index]
+}
+
+// Sync indexes available blocks for the active index, as well as the
+// shadow index if there is one, then switches to the shadow index.
+func (p *Program) Sync() error {
+	err := p.active.indexer.Sync()
+	if err != nil {
+		return err
+	}
+	if p.shadow == nil {
+		return nil
+	}
+	_, err = p.trySwitch()
+	return err
+}
+
+// Run keeps indexing new blocks, the shadow index is built in background so
+// the active index keeps following the chain.
+func (p *Program) Run() error {
+	var shadowDone chan error
+	caughtUp := false
+	if p.shadow != nil {
+		shadowDone = make(chan error, 1)
+		go func(i *Indexer) {
+			shadowDone <- i.Sync()
+		}(p.shadow.indexer)
+	}
+	for {
+		err := p.active.indexer.Sync()
+		if err != nil {
+			return err
+		}
+		select {
+		case err = <-shadowDone:
+			if err != nil {
+				return err
+			}
+			shadowDone = nil
+			caughtUp = true
+		default:
+		}
+		if caughtUp {
+			switched, err := p.trySwitch()
+			if err != nil {
+				return err
+			}
+			caughtUp = !switched
+		}
+		time.Sleep(time.Second)
+	}
+}
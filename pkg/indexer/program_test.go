@@ -0,0 +1,327 @@
+package indexer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/xxuejie/animagus/pkg/ast"
+	"github.com/xxuejie/animagus/pkg/source"
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+// renamedAst returns the test AST with the call renamed, so it is indexed
+// into different keys.
+func renamedAst(t *testing.T, name string) []byte {
+	root := &ast.Root{}
+	err := proto.Unmarshal(testAst(t), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.GetCalls()[0].Name = name
+	content, err := proto.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func astHash(t *testing.T, astContent []byte) []byte {
+	hash, err := AstHash(astContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func newTestProgram(t *testing.T, s store.Store, src source.Source, astContent []byte) *Program {
+	p, err := NewProgram("p", astContent, s, func(store.Store) (source.Source, error) {
+		return src, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// assertIndexes checks all keys of s belong to the indexes of hashes, or
+// queries used by them.
+func assertIndexes(t *testing.T, s *store.MemoryStore, hashes ...[]byte) {
+	fingerprints := make(map[string]bool)
+	for _, hash := range hashes {
+		content, _ := s.Get(IndexKeyPrefix(hash) + astKey)
+		i, err := NewIndexer(content, s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fingerprint := range i.Fingerprints() {
+			fingerprints[fingerprint] = true
+		}
+	}
+	found := make(map[string]bool)
+	for _, key := range s.Keys() {
+		if key == activeIndexKey || key == indexesKey || key == queriesKey ||
+			fingerprints[queryFingerprintOfKey(key)] {
+			continue
+		}
+		valid := false
+		for _, hash := range hashes {
+			if strings.HasPrefix(key, IndexKeyPrefix(hash)) {
+				valid = true
+				found[IndexKeyPrefix(hash)] = true
+			}
+		}
+		if !valid {
+			t.Errorf("Key %s does not belong to any index", key)
+		}
+	}
+	members, err := s.Members(indexesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != len(hashes) || len(members) != len(hashes) {
+		t.Errorf("Invalid indexes: %v, expected: %d", members, len(hashes))
+	}
+	members, err = s.Members(queriesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != len(fingerprints) {
+		t.Errorf("Invalid queries: %v, expected: %d", members, len(fingerprints))
+	}
+}
+
+func TestProgramReindex(t *testing.T) {
+	src := testChain(t, 5)
+	s := store.NewMemoryStore()
+	oldAst := renamedAst(t, "old")
+	err := newTestProgram(t, s, src, oldAst).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeHash, _ := s.Get(activeIndexKey)
+	if !bytes.Equal(activeHash, astHash(t, oldAst)) {
+		t.Fatalf("Invalid active index: %x", activeHash)
+	}
+
+	newAst := renamedAst(t, "new")
+	p := newTestProgram(t, s, src, newAst)
+	activeContent, _, _ := p.Active()
+	if !bytes.Equal(activeContent, oldAst) {
+		t.Fatal("Old index should be served till new index catches up!")
+	}
+	_, err = src.AppendTransactions(testTx(nil, testOutput{10, 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var switched []byte
+	p.OnSwitch(func(astContent []byte, s store.Store, src source.Source) error {
+		switched = astContent
+		return nil
+	})
+	err = p.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(switched, newAst) {
+		t.Fatal("Program is not switched to new index!")
+	}
+	activeHash, _ = s.Get(activeIndexKey)
+	if !bytes.Equal(activeHash, astHash(t, newAst)) {
+		t.Fatalf("Invalid active index: %x", activeHash)
+	}
+	assertIndexes(t, s, astHash(t, newAst))
+
+	expected := store.NewMemoryStore()
+	err = newTestProgram(t, expected, src, newAst).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameStore(t, s, expected, "")
+}
+
+func TestProgramCollectGarbage(t *testing.T) {
+	src := testChain(t, 3)
+	s := store.NewMemoryStore()
+	a, b, c := renamedAst(t, "a"), renamedAst(t, "b"), renamedAst(t, "c")
+	err := newTestProgram(t, s, src, a).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Index of b is abandoned before catching up
+	newTestProgram(t, s, src, b)
+	assertIndexes(t, s, astHash(t, a), astHash(t, b))
+	p := newTestProgram(t, s, src, c)
+	assertIndexes(t, s, astHash(t, a), astHash(t, c))
+	err = p.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIndexes(t, s, astHash(t, c))
+
+	// An index built by an incompatible indexer version cannot be kept
+	batch := &store.Batch{}
+	batch.Set(IndexKeyPrefix(astHash(t, c))+astKey, []byte("outdated"))
+	err = s.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = newTestProgram(t, s, src, a)
+	activeContent, _, _ := p.Active()
+	if !bytes.Equal(activeContent, a) {
+		t.Error("Incompatible index should not be served!")
+	}
+	assertIndexes(t, s, astHash(t, a))
+
+	// Keys written into the program keyspace before index keyspaces
+	batch = &store.Batch{}
+	for _, key := range []string{"AST_HASH", "LAST_BLOCK", "BLOCK:1:HASH", "CALL:old:QUERY:0:PARAM:0:CELLS"} {
+		batch.Set(key, []byte{1})
+	}
+	err = s.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTestProgram(t, s, src, a)
+	assertIndexes(t, s, astHash(t, a))
+}
+
+// extendedAst returns the test AST with one more call, which is indexed by
+// a different query.
+func extendedAst(t *testing.T) []byte {
+	root := &ast.Root{}
+	err := proto.Unmarshal(testAst(t), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.Calls = append(root.Calls, &ast.Call{
+		Name: "capacities",
+		Result: &ast.Value{
+			T: ast.Value_QUERY_CELLS,
+			Children: []*ast.Value{
+				&ast.Value{
+					T: ast.Value_EQUAL,
+					Children: []*ast.Value{
+						&ast.Value{
+							T:        ast.Value_GET_CAPACITY,
+							Children: []*ast.Value{arg(0)},
+						},
+						param(0),
+					},
+				},
+			},
+		},
+	})
+	content, err := proto.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+type recordingStore struct {
+	*store.MemoryStore
+	keys []string
+}
+
+func (s *recordingStore) Commit(batch *store.Batch) error {
+	for _, op := range batch.Ops {
+		s.keys = append(s.keys, op.Key)
+	}
+	return s.MemoryStore.Commit(batch)
+}
+
+func TestProgramIncrementalReindex(t *testing.T) {
+	src := testChain(t, 6)
+	s := &recordingStore{MemoryStore: store.NewMemoryStore()}
+	err := newTestProgram(t, s, src, testAst(t)).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := newTestProgram(t, s, src, extendedAst(t))
+	reused := p.active.indexer.Fingerprints()[0]
+	added := p.shadow.indexer.Fingerprints()[1]
+	_, err = src.AppendTransactions(testTx(nil, testOutput{10, 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.keys = nil
+	err = p.shadow.indexer.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addedKeys := 0
+	for _, key := range s.keys {
+		switch queryFingerprintOfKey(key) {
+		case reused:
+			t.Fatalf("Reused query is indexed again: %s", key)
+		case added:
+			addedKeys++
+		}
+	}
+	if addedKeys == 0 {
+		t.Fatal("New query is not indexed!")
+	}
+	err = p.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeContent, _, _ := p.Active()
+	if !bytes.Equal(activeContent, extendedAst(t)) {
+		t.Fatal("Program is not switched to new index!")
+	}
+	expected := store.NewMemoryStore()
+	err = newTestProgram(t, expected, src, extendedAst(t)).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameStore(t, s.MemoryStore, expected, "")
+
+	// Blocks indexed before the switch can be reverted for both queries
+	src.Rollback(3)
+	for j := 0; j < 5; j++ {
+		_, err = src.AppendTransactions(testTx(nil, testOutput{uint64(j), 6}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = p.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = store.NewMemoryStore()
+	err = newTestProgram(t, expected, src, extendedAst(t)).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameStore(t, s.MemoryStore, expected, "")
+	assertIndexes(t, s.MemoryStore, astHash(t, extendedAst(t)))
+}
+
+func TestDeleteLegacyKeys(t *testing.T) {
+	s := store.NewMemoryStore()
+	deleted, err := DeleteLegacyKeys(s)
+	if err != nil || deleted {
+		t.Fatalf("Empty store has no legacy keys: %t, error: %v", deleted, err)
+	}
+	batch := &store.Batch{}
+	for _, key := range []string{"AST_HASH", "LAST_BLOCK", "BLOCK:1:HASH", "BLOCK:1:REVERT_COMMANDS",
+		"CALL:balance:QUERY:0:PARAM:0:CELLS", "CELL:00:0"} {
+		batch.Set(key, []byte{1})
+	}
+	batch.Set(ProgramKeyPrefix("p")+"ACTIVE_INDEX", []byte{1})
+	err = s.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err = DeleteLegacyKeys(s)
+	if err != nil || !deleted {
+		t.Fatalf("Legacy keys are not deleted: %t, error: %v", deleted, err)
+	}
+	keys := s.Keys()
+	if len(keys) != 1 || keys[0] != ProgramKeyPrefix("p")+"ACTIVE_INDEX" {
+		t.Errorf("Invalid keys: %v", keys)
+	}
+}
@@ -0,0 +1,74 @@
+package indexer
+
+import (
+	"sync"
+	"time"
+)
+
+// progressWindow is the duration blocks per second are averaged over.
+const progressWindow = time.Minute
+
+// Progress reports indexing progress that is not kept in the store.
+type Progress struct {
+	BlocksPerSecond float64
+	// LastReorgBlockNumber is the block reverted last, LastReorgTime is zero
+	// when no block is reverted yet.
+	LastReorgBlockNumber uint64
+	LastReorgTime        time.Time
+}
+
+type progressSample struct {
+	time   time.Time
+	blocks uint64
+}
+
+// progressMeter is updated by the indexer and read by other goroutines.
+type progressMeter struct {
+	mutex sync.Mutex
+	// samples keep the number of blocks indexed per second in the window.
+	samples              []progressSample
+	lastReorgBlockNumber uint64
+	lastReorgTime        time.Time
+}
+
+func (m *progressMeter) indexed(blocks uint64, now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	second := now.Truncate(time.Second)
+	if len(m.samples) > 0 && m.samples[len(m.samples)-1].time.Equal(second) {
+		m.samples[len(m.samples)-1].blocks += blocks
+	} else {
+		m.samples = append(m.samples, progressSample{time: second, blocks: blocks})
+	}
+	m.expire(now)
+}
+
+func (m *progressMeter) reverted(blockNumber uint64, now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lastReorgBlockNumber = blockNumber
+	m.lastReorgTime = now
+}
+
+func (m *progressMeter) expire(now time.Time) {
+	start := 0
+	for start < len(m.samples) && now.Sub(m.samples[start].time) >= progressWindow {
+		start++
+	}
+	m.samples = m.samples[start:]
+}
+
+func (m *progressMeter) progress(now time.Time) Progress {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.expire(now)
+	var blocks uint64
+	for _, sample := range m.samples {
+		blocks += sample.blocks
+	}
+	return Progress{
+		BlocksPerSecond:      float64(blocks) / progressWindow.Seconds(),
+		LastReorgBlockNumber: m.lastReorgBlockNumber,
+		LastReorgTime:        m.lastReorgTime,
+	}
+}
@@ -0,0 +1,32 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressMeter(t *testing.T) {
+	m := &progressMeter{}
+	start := time.Unix(1000, 0)
+	m.indexed(30, start)
+	m.indexed(30, start.Add(500*time.Millisecond))
+	m.indexed(60, start.Add(30*time.Second))
+	if bps := m.progress(start.Add(40 * time.Second)).BlocksPerSecond; bps != 2 {
+		t.Errorf("Invalid blocks per second: %f", bps)
+	}
+	// Blocks indexed more than a minute ago are not counted
+	if bps := m.progress(start.Add(80 * time.Second)).BlocksPerSecond; bps != 1 {
+		t.Errorf("Invalid blocks per second: %f", bps)
+	}
+	if len(m.samples) != 1 {
+		t.Errorf("Invalid number of samples: %d", len(m.samples))
+	}
+	if !m.progress(start).LastReorgTime.IsZero() {
+		t.Error("No reorg should be reported!")
+	}
+	m.reverted(5, start.Add(time.Minute))
+	progress := m.progress(start.Add(2 * time.Minute))
+	if progress.LastReorgBlockNumber != 5 || !progress.LastReorgTime.Equal(start.Add(time.Minute)) {
+		t.Errorf("Invalid last reorg: %v", progress)
+	}
+}
@@ -0,0 +1,102 @@
+package indexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+// assertBlocks checks hashes and revert commands are only kept for blocks
+// from start to end.
+func assertBlocks(t *testing.T, s *store.MemoryStore, start uint64, end uint64) {
+	expected := make(map[string]bool)
+	for n := start; n <= end; n++ {
+		expected[blockHashKey(n)] = true
+		expected[revertCommandsKey(n)] = true
+	}
+	for _, key := range s.Keys() {
+		if !strings.HasPrefix(key, "BLOCK:") {
+			continue
+		}
+		if !expected[key] {
+			t.Errorf("Key %s should be pruned", key)
+		}
+		delete(expected, key)
+	}
+	for key := range expected {
+		t.Errorf("Key %s is missing", key)
+	}
+}
+
+func TestReorgDepth(t *testing.T) {
+	src := testChain(t, 10)
+	s := store.NewMemoryStore()
+	i := newTestIndexer(t, s, src)
+	i.SetReorgDepth(3)
+	err := i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBlocks(t, s, 7, 9)
+
+	// Reorgs within reorg depth are reverted
+	src.Rollback(8)
+	for j := 0; j < 3; j++ {
+		_, err = src.AppendTransactions(testTx(nil, testOutput{uint64(j), 5}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBlocks(t, s, 8, 10)
+	expected := store.NewMemoryStore()
+	err = newTestIndexer(t, expected, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := uint64(0); n < 8; n++ {
+		batch := &store.Batch{}
+		batch.Delete(blockHashKey(n))
+		batch.Delete(revertCommandsKey(n))
+		err = expected.Commit(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertSameStore(t, s, expected, "")
+
+	// Deeper reorgs cannot be reverted
+	src.Rollback(6)
+	for j := 0; j < 6; j++ {
+		_, err = src.AppendTransactions(testTx(nil, testOutput{uint64(j), 6}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = i.Sync()
+	if err == nil || !strings.Contains(err.Error(), "block 7 is deeper") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestReorgDepthPrunesOldBlocks(t *testing.T) {
+	src := testChain(t, 6)
+	s := store.NewMemoryStore()
+	err := newTestIndexer(t, s, src).Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBlocks(t, s, 0, 5)
+
+	i := newTestIndexer(t, s, src)
+	i.SetReorgDepth(2)
+	err = i.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBlocks(t, s, 4, 5)
+}
@@ -0,0 +1,81 @@
+package rpctypes
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+const ShannonsPerByte uint64 = 100000000
+
+type EpochNumberWithFraction uint64
+
+func (e EpochNumberWithFraction) Number() uint64 {
+	return uint64(e) & 0xFFFFFF
+}
+
+func (e EpochNumberWithFraction) Index() uint64 {
+	return (uint64(e) >> 24) & 0xFFFF
+}
+
+func (e EpochNumberWithFraction) Length() uint64 {
+	return (uint64(e) >> 40) & 0xFFFF
+}
+
+type DaoField struct {
+	C  uint64
+	AR uint64
+	S  uint64
+	U  uint64
+}
+
+func ParseDaoField(dao []byte) (DaoField, error) {
+	if len(dao) != 32 {
+		return DaoField{}, fmt.Errorf("DAO field should be exactly 32 bytes!")
+	}
+	return DaoField{
+		C:  binary.LittleEndian.Uint64(dao[0:8]),
+		AR: binary.LittleEndian.Uint64(dao[8:16]),
+		S:  binary.LittleEndian.Uint64(dao[16:24]),
+		U:  binary.LittleEndian.Uint64(dao[24:32]),
+	}, nil
+}
+
+func (s Script) OccupiedBytes() uint64 {
+	return uint64(len(s.CodeHash)) + 1 + uint64(len(s.Args))
+}
+
+func (c CellOutput) OccupiedCapacity(dataLength uint64) (uint64, error) {
+	occupiedBytes := 8 + c.Lock.OccupiedBytes() + dataLength
+	if c.Type != nil {
+		occupiedBytes += c.Type.OccupiedBytes()
+	}
+	if occupiedBytes > math.MaxUint64/ShannonsPerByte {
+		return 0, fmt.Errorf("Occupied capacity overflow!")
+	}
+	return occupiedBytes * ShannonsPerByte, nil
+}
+
+// CalculateMaximumWithdraw follows the Nervos DAO rule: occupied capacity
+// earns no interest, while the rest grows with the accumulated rate(AR)
+// between the deposit header and the withdrawing header.
+func CalculateMaximumWithdraw(output CellOutput, dataLength uint64, depositDao DaoField, withdrawingDao DaoField) (uint64, error) {
+	if depositDao.AR == 0 {
+		return 0, fmt.Errorf("Invalid deposit accumulated rate!")
+	}
+	occupiedCapacity, err := output.OccupiedCapacity(dataLength)
+	if err != nil {
+		return 0, err
+	}
+	if uint64(output.Capacity) < occupiedCapacity {
+		return 0, fmt.Errorf("Cell capacity %d is less than occupied capacity %d!", output.Capacity, occupiedCapacity)
+	}
+	counted := new(big.Int).SetUint64(uint64(output.Capacity) - occupiedCapacity)
+	counted.Mul(counted, new(big.Int).SetUint64(withdrawingDao.AR))
+	counted.Div(counted, new(big.Int).SetUint64(depositDao.AR))
+	if !counted.IsUint64() || counted.Uint64() > math.MaxUint64-occupiedCapacity {
+		return 0, fmt.Errorf("Withdraw capacity overflow!")
+	}
+	return counted.Uint64() + occupiedCapacity, nil
+}
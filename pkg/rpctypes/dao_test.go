@@ -0,0 +1,43 @@
+package rpctypes
+
+import (
+	"testing"
+)
+
+func TestEpochNumberWithFraction(t *testing.T) {
+	epoch := EpochNumberWithFraction(0x7080291000032)
+	if epoch.Number() != 50 || epoch.Index() != 657 || epoch.Length() != 1800 {
+		t.Errorf("Invalid epoch: %d %d/%d", epoch.Number(), epoch.Index(), epoch.Length())
+	}
+}
+
+func TestParseDaoField(t *testing.T) {
+	var dao Raw
+	err := dao.UnmarshalJSON([]byte(`"0x1691917aca04d52ed56f567884902300727d28158db20a0000d9ddaac2150007"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, err := ParseDaoField(dao)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.C != 0x2ed504ca7a919116 || field.AR != 0x23908478566fd5 ||
+		field.S != 0xab28d15287d72 || field.U != 0x70015c2aaddd900 {
+		t.Errorf("Invalid DAO field: %+v", field)
+	}
+}
+
+func TestCalculateMaximumWithdraw(t *testing.T) {
+	output := CellOutput{
+		Capacity: Uint64(1000000 * ShannonsPerByte),
+	}
+	deposit := DaoField{AR: 10000000000123456}
+	withdrawing := DaoField{AR: 10000000001123456}
+	capacity, err := CalculateMaximumWithdraw(output, 10, deposit, withdrawing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capacity != 100000000009999 {
+		t.Errorf("Invalid maximum withdraw: %d, expected: %d", capacity, uint64(100000000009999))
+	}
+}
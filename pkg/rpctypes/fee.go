@@ -0,0 +1,43 @@
+package rpctypes
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+)
+
+const Secp256k1SignatureSize uint64 = 65
+
+// SerializedSizeInBlock returns the size used by CKB for fee calculation,
+// which includes 4 extra bytes for the offset of the transaction in a block.
+func (t Transaction) SerializedSizeInBlock() (uint64, error) {
+	var buffer bytes.Buffer
+	err := t.SerializeToCore(&buffer)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(buffer.Len()) + 4, nil
+}
+
+// CalculateFee returns the fee for a transaction of the given size at a fee
+// rate in shannons per KB, rounding up like CKB does.
+func CalculateFee(size uint64, feeRate uint64) (uint64, error) {
+	fee := new(big.Int).SetUint64(size)
+	fee.Mul(fee, new(big.Int).SetUint64(feeRate))
+	fee.Add(fee, big.NewInt(999))
+	fee.Div(fee, big.NewInt(1000))
+	if !fee.IsUint64() {
+		return 0, fmt.Errorf("Fee overflow!")
+	}
+	return fee.Uint64(), nil
+}
+
+func WitnessPlaceholder(lockSize uint64) ([]byte, error) {
+	lock := Bytes(make([]byte, lockSize))
+	var buffer bytes.Buffer
+	err := WitnessArgs{Lock: &lock}.SerializeToCore(&buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,34 @@
+package rpctypes
+
+import (
+	"testing"
+)
+
+func TestCalculateFee(t *testing.T) {
+	cases := [][3]uint64{
+		{0, 1000, 0},
+		{1, 1, 1},
+		{1000, 1000, 1000},
+		{1001, 1000, 1001},
+		{545, 1500, 818},
+	}
+	for _, c := range cases {
+		fee, err := CalculateFee(c[0], c[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fee != c[2] {
+			t.Errorf("Invalid fee for size %d at rate %d: %d, expected: %d", c[0], c[1], fee, c[2])
+		}
+	}
+}
+
+func TestWitnessPlaceholder(t *testing.T) {
+	placeholder, err := WitnessPlaceholder(Secp256k1SignatureSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placeholder) != 85 {
+		t.Errorf("Invalid placeholder length: %d", len(placeholder))
+	}
+}
@@ -0,0 +1,110 @@
+package rpctypes
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/xxuejie/animagus/pkg/coretypes"
+)
+
+const (
+	multisigReserved   byte = 0
+	multisigHeaderSize      = 4
+	pubkeyHashSize          = 20
+)
+
+// MultisigConfig describes the multi_sig_script used by
+// secp256k1_blake160_multisig_all lock.
+type MultisigConfig struct {
+	RequireFirstN uint64
+	Threshold     uint64
+	PubkeyHashes  [][]byte
+}
+
+func (c MultisigConfig) Verify() error {
+	if len(c.PubkeyHashes) == 0 || len(c.PubkeyHashes) > 255 {
+		return fmt.Errorf("Invalid number of pubkey hashes: %d", len(c.PubkeyHashes))
+	}
+	if c.Threshold == 0 || c.Threshold > uint64(len(c.PubkeyHashes)) {
+		return fmt.Errorf("Invalid threshold: %d", c.Threshold)
+	}
+	if c.RequireFirstN > c.Threshold {
+		return fmt.Errorf("Invalid require first n: %d", c.RequireFirstN)
+	}
+	for i, h := range c.PubkeyHashes {
+		if len(h) != pubkeyHashSize {
+			return fmt.Errorf("Pubkey hash %d should be exactly 20 bytes!", i)
+		}
+	}
+	return nil
+}
+
+func (c MultisigConfig) Script() ([]byte, error) {
+	if err := c.Verify(); err != nil {
+		return nil, err
+	}
+	script := []byte{
+		multisigReserved,
+		byte(c.RequireFirstN),
+		byte(c.Threshold),
+		byte(len(c.PubkeyHashes)),
+	}
+	for _, h := range c.PubkeyHashes {
+		script = append(script, h...)
+	}
+	return script, nil
+}
+
+// LockArgs returns blake160 of the multisig script, followed by since in
+// little endian when the lock is time locked.
+func (c MultisigConfig) LockArgs(since *uint64) ([]byte, error) {
+	script, err := c.Script()
+	if err != nil {
+		return nil, err
+	}
+	h, err := CalculateHash(Raw(script))
+	if err != nil {
+		return nil, err
+	}
+	args := h[:20]
+	if since != nil {
+		if err := VerifySince(*since); err != nil {
+			return nil, err
+		}
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], *since)
+		args = append(args, b[:]...)
+	}
+	return args, nil
+}
+
+// WitnessLock returns the multisig script followed by zero-filled space for
+// threshold signatures, which has the same size as the signed lock.
+func (c MultisigConfig) WitnessLock() ([]byte, error) {
+	script, err := c.Script()
+	if err != nil {
+		return nil, err
+	}
+	return append(script, make([]byte, Secp256k1SignatureSize*c.Threshold)...), nil
+}
+
+// MultisigSigningWitness prepares the first witness of a multisig group for
+// signing message generation: the multisig script is kept in the lock while
+// signatures are zero-filled.
+func MultisigSigningWitness(witness []byte) ([]byte, error) {
+	w := coretypes.WitnessArgs(witness)
+	if len(witness) == 0 || !w.Verify(false) || !w.HasLock() {
+		return nil, fmt.Errorf("Multisig witness must contain a lock with multisig script!")
+	}
+	lock := w.Lock().Value()
+	if len(lock) < multisigHeaderSize {
+		return nil, fmt.Errorf("Invalid multisig lock!")
+	}
+	scriptSize := multisigHeaderSize + pubkeyHashSize*int(lock[3])
+	if len(lock) != scriptSize+int(Secp256k1SignatureSize)*int(lock[2]) {
+		return nil, fmt.Errorf("Invalid multisig lock length: %d", len(lock))
+	}
+	signingLock := make([]byte, len(lock))
+	copy(signingLock, lock[:scriptSize])
+	return WitnessWithLock(witness, signingLock)
+}
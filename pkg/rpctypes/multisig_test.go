@@ -0,0 +1,76 @@
+package rpctypes
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func testMultisigConfig() MultisigConfig {
+	return MultisigConfig{
+		RequireFirstN: 1,
+		Threshold:     2,
+		PubkeyHashes: [][]byte{
+			bytes.Repeat([]byte{1}, 20),
+			bytes.Repeat([]byte{2}, 20),
+			bytes.Repeat([]byte{3}, 20),
+		},
+	}
+}
+
+func TestMultisigLockArgs(t *testing.T) {
+	config := testMultisigConfig()
+	args, err := config.LockArgs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(args) != "a0ea3bde34a3b0a4ea7075c63789457636ecdfa4" {
+		t.Errorf("Invalid multisig lock args: %x", args)
+	}
+	since := uint64(0x2000000000000064)
+	args, err = config.LockArgs(&since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(args) != "a0ea3bde34a3b0a4ea7075c63789457636ecdfa46400000000000020" {
+		t.Errorf("Invalid multisig lock args with since: %x", args)
+	}
+
+	config.Threshold = 4
+	_, err = config.LockArgs(nil)
+	if err == nil {
+		t.Errorf("Threshold larger than pubkey hashes is accepted!")
+	}
+}
+
+func TestMultisigSigningWitness(t *testing.T) {
+	config := testMultisigConfig()
+	lock, err := config.WitnessLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock) != 4+20*3+65*2 {
+		t.Errorf("Invalid multisig witness lock size: %d", len(lock))
+	}
+	// Filling in signatures should not affect the signing witness.
+	for i := 64; i < len(lock); i++ {
+		lock[i] = 0xFF
+	}
+	witness, err := WitnessWithLock(nil, lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingWitness, err := MultisigSigningWitness(witness)
+	if err != nil {
+		t.Fatal(err)
+	}
+	placeholder, _ := config.WitnessLock()
+	expected, _ := WitnessWithLock(nil, placeholder)
+	if !bytes.Equal(signingWitness, expected) {
+		t.Errorf("Invalid multisig signing witness: %x", signingWitness)
+	}
+	_, err = MultisigSigningWitness(nil)
+	if err == nil {
+		t.Errorf("Empty multisig witness is accepted!")
+	}
+}
@@ -154,8 +154,9 @@ func (h ProposalShortId) MarshalJSON() ([]byte, error) {
 type ScriptHashType byte
 
 const (
-	Data ScriptHashType = 0
-	Type ScriptHashType = 1
+	Data  ScriptHashType = 0
+	Type  ScriptHashType = 1
+	Data1 ScriptHashType = 2
 )
 
 func (t *ScriptHashType) UnmarshalJSON(b []byte) error {
@@ -168,6 +169,8 @@ func (t *ScriptHashType) UnmarshalJSON(b []byte) error {
 		*t = Data
 	case "type":
 		*t = Type
+	case "data1":
+		*t = Data1
 	default:
 		return fmt.Errorf("Invalid script hash type!")
 	}
@@ -181,6 +184,8 @@ func (t ScriptHashType) MarshalJSON() ([]byte, error) {
 		s = "data"
 	case Type:
 		s = "type"
+	case Data1:
+		s = "data1"
 	default:
 		return nil, fmt.Errorf("Invalid script hash type!")
 	}
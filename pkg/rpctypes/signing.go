@@ -0,0 +1,113 @@
+package rpctypes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/xxuejie/animagus/pkg/coretypes"
+)
+
+// WitnessWithLock replaces the lock field of a serialized WitnessArgs, an
+// empty witness is treated as a WitnessArgs with no fields set.
+func WitnessWithLock(witness []byte, lock []byte) ([]byte, error) {
+	l := Bytes(lock)
+	args := WitnessArgs{Lock: &l}
+	if len(witness) > 0 {
+		w := coretypes.WitnessArgs(witness)
+		if !w.Verify(false) {
+			return nil, fmt.Errorf("Invalid WitnessArgs!")
+		}
+		if inputType := w.MaybeInputType(); inputType != nil {
+			b := Bytes(inputType.Value())
+			args.InputType = &b
+		}
+		if outputType := w.MaybeOutputType(); outputType != nil {
+			b := Bytes(outputType.Value())
+			args.OutputType = &b
+		}
+	}
+	var buffer bytes.Buffer
+	err := args.SerializeToCore(&buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// SigningWitness returns the witness with its lock zero-filled, which is
+// how the first witness of a group looks when generating signing message.
+// When the witness has no lock, lockSize bytes are reserved.
+func SigningWitness(witness []byte, lockSize uint64) ([]byte, error) {
+	if len(witness) == 0 {
+		return WitnessPlaceholder(lockSize)
+	}
+	w := coretypes.WitnessArgs(witness)
+	if !w.Verify(false) {
+		return nil, fmt.Errorf("Invalid WitnessArgs!")
+	}
+	if lock := w.MaybeLock(); lock != nil {
+		lockSize = uint64(len(lock.Value()))
+	}
+	return WitnessWithLock(witness, make([]byte, lockSize))
+}
+
+// SigningMessage calculates the message to sign for a group of inputs
+// sharing the same lock, following secp256k1_blake160_sighash_all: the tx
+// hash, then the first witness of the group with a zero-filled lock, the
+// rest witnesses in the group, and finally witnesses beyond the inputs.
+func (t Transaction) SigningMessage(group []int, lockSize uint64) ([]byte, error) {
+	if len(group) == 0 {
+		return nil, fmt.Errorf("Empty input group!")
+	}
+	first, err := SigningWitness(t.witness(group[0]), lockSize)
+	if err != nil {
+		return nil, fmt.Errorf("Witness %d: %s", group[0], err)
+	}
+	return t.SigningMessageWithWitness(group, first)
+}
+
+// SigningMessageWithWitness works like SigningMessage, but uses the given
+// witness in place of the first witness of the group, this is for locks
+// that prepare the first witness differently.
+func (t Transaction) SigningMessageWithWitness(group []int, first []byte) ([]byte, error) {
+	if len(group) == 0 {
+		return nil, fmt.Errorf("Empty input group!")
+	}
+	for _, i := range group {
+		if i < 0 || i >= len(t.Inputs) {
+			return nil, fmt.Errorf("Input index %d is out of bound!", i)
+		}
+	}
+	txHash, err := CalculateHash(t.RawTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := newBlake2b()
+	if err != nil {
+		return nil, err
+	}
+	writeWitness := func(w []byte) {
+		var length [8]byte
+		binary.LittleEndian.PutUint64(length[:], uint64(len(w)))
+		h.Write(length[:])
+		h.Write(w)
+	}
+	h.Write(txHash)
+	writeWitness(first)
+	for _, i := range group[1:] {
+		writeWitness(t.witness(i))
+	}
+	for i := len(t.Inputs); i < len(t.Witnesses); i++ {
+		writeWitness(t.Witnesses[i])
+	}
+	return h.Sum(nil), nil
+}
+
+func (t Transaction) witness(i int) []byte {
+	if i < len(t.Witnesses) {
+		return t.Witnesses[i]
+	}
+	return []byte{}
+}
@@ -0,0 +1,74 @@
+package rpctypes
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func signingTestTransaction() Transaction {
+	tx := Transaction{
+		RawTransaction: RawTransaction{
+			CellDeps:   []CellDep{},
+			HeaderDeps: []Hash{},
+			Inputs: []CellInput{
+				CellInput{PreviousOutput: OutPoint{Index: 0}},
+				CellInput{PreviousOutput: OutPoint{Index: 1}},
+				CellInput{PreviousOutput: OutPoint{Index: 2}},
+			},
+			Outputs: []CellOutput{
+				CellOutput{Capacity: Uint64(100 * ShannonsPerByte)},
+			},
+			OutputsData: []Bytes{Bytes{}},
+		},
+		Witnesses: []Bytes{Bytes{}, Bytes{}, Bytes{0x01}, Bytes{0x02, 0x03}},
+	}
+	tx.Inputs[0].PreviousOutput.TxHash[0] = 1
+	return tx
+}
+
+func TestSigningMessage(t *testing.T) {
+	tx := signingTestTransaction()
+	cases := []struct {
+		group    []int
+		expected string
+	}{
+		{[]int{0, 2}, "e920f4c1fbbbc65ba5223687e7a98dcd161fe325bbb526e77c59c28fffb5a831"},
+		{[]int{1}, "f5066693fae6acae1d88721d178763b8a3f078267013b340c9977c2903615732"},
+	}
+	for _, c := range cases {
+		message, err := tx.SigningMessage(c.group, Secp256k1SignatureSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(message) != c.expected {
+			t.Errorf("Invalid signing message for group %v: %x", c.group, message)
+		}
+	}
+	_, err := tx.SigningMessage([]int{2}, Secp256k1SignatureSize)
+	if err == nil {
+		t.Errorf("Invalid WitnessArgs is accepted!")
+	}
+}
+
+func TestSigningWitness(t *testing.T) {
+	witness, err := SigningWitness(nil, Secp256k1SignatureSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(witness) != 85 {
+		t.Errorf("Invalid signing witness length: %d", len(witness))
+	}
+	// An existing lock keeps its length while being zero-filled.
+	signed, err := WitnessWithLock(nil, []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	witness, err = SigningWitness(signed, Secp256k1SignatureSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := WitnessWithLock(nil, []byte{0, 0, 0})
+	if hex.EncodeToString(witness) != hex.EncodeToString(expected) {
+		t.Errorf("Invalid signing witness: %x", witness)
+	}
+}
@@ -0,0 +1,71 @@
+package rpctypes
+
+import (
+	"fmt"
+)
+
+const (
+	SinceMetricBlockNumber uint64 = 0
+	SinceMetricEpoch       uint64 = 1
+	SinceMetricTimestamp   uint64 = 2
+
+	SinceMaximumValue uint64 = 1<<56 - 1
+
+	sinceRelativeFlag uint64 = 1 << 63
+	sinceMetricShift  uint64 = 61
+	sinceMetricMask   uint64 = 0x3 << sinceMetricShift
+	sinceReservedMask uint64 = 0x1F << 56
+)
+
+func verifySinceValue(metric uint64, value uint64) error {
+	if value > SinceMaximumValue {
+		return fmt.Errorf("Since value %d exceeds 56 bits!", value)
+	}
+	switch metric {
+	case SinceMetricBlockNumber, SinceMetricTimestamp:
+	case SinceMetricEpoch:
+		epoch := EpochNumberWithFraction(value)
+		if epoch.Length() == 0 && epoch.Index() != 0 {
+			return fmt.Errorf("Invalid epoch fraction in since: %d/%d", epoch.Index(), epoch.Length())
+		}
+		if epoch.Length() > 0 && epoch.Index() >= epoch.Length() {
+			return fmt.Errorf("Invalid epoch fraction in since: %d/%d", epoch.Index(), epoch.Length())
+		}
+	default:
+		return fmt.Errorf("Invalid since metric: %d", metric)
+	}
+	return nil
+}
+
+func EncodeSince(metric uint64, relative bool, value uint64) (uint64, error) {
+	if err := verifySinceValue(metric, value); err != nil {
+		return 0, err
+	}
+	since := metric<<sinceMetricShift | value
+	if relative {
+		since |= sinceRelativeFlag
+	}
+	return since, nil
+}
+
+func DecodeSince(since uint64) (metric uint64, relative bool, value uint64, err error) {
+	if since&sinceReservedMask != 0 {
+		err = fmt.Errorf("Reserved bits in since %x are not zero!", since)
+		return
+	}
+	metric = (since & sinceMetricMask) >> sinceMetricShift
+	relative = since&sinceRelativeFlag != 0
+	value = since & SinceMaximumValue
+	err = verifySinceValue(metric, value)
+	return
+}
+
+// VerifySince checks a since value to be used in a cell input, zero means no
+// restriction on the input and is always valid.
+func VerifySince(since uint64) error {
+	if since == 0 {
+		return nil
+	}
+	_, _, _, err := DecodeSince(since)
+	return err
+}
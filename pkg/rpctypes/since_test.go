@@ -0,0 +1,48 @@
+package rpctypes
+
+import (
+	"testing"
+)
+
+func TestEncodeSince(t *testing.T) {
+	since, err := EncodeSince(SinceMetricEpoch, false, 0x7080291000032)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since != 0x2007080291000032 {
+		t.Errorf("Invalid since: %x", since)
+	}
+	since, err = EncodeSince(SinceMetricBlockNumber, true, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since != 0x8000000000000064 {
+		t.Errorf("Invalid since: %x", since)
+	}
+	_, err = EncodeSince(SinceMetricTimestamp, false, 1<<56)
+	if err == nil {
+		t.Errorf("Since value overflow is accepted!")
+	}
+	_, err = EncodeSince(SinceMetricEpoch, true, 0x10002000032)
+	if err == nil {
+		t.Errorf("Invalid epoch fraction is accepted!")
+	}
+}
+
+func TestDecodeSince(t *testing.T) {
+	metric, relative, value, err := DecodeSince(0xc000000000000e10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metric != SinceMetricTimestamp || !relative || value != 3600 {
+		t.Errorf("Invalid since decoding: %d %t %d", metric, relative, value)
+	}
+	_, _, _, err = DecodeSince(0x6000000000000001)
+	if err == nil {
+		t.Errorf("Invalid since metric is accepted!")
+	}
+	_, _, _, err = DecodeSince(0x0100000000000001)
+	if err == nil {
+		t.Errorf("Since with reserved bits is accepted!")
+	}
+}
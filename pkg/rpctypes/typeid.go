@@ -0,0 +1,26 @@
+package rpctypes
+
+import (
+	"encoding/binary"
+)
+
+// TypeIDCodeHash is the code hash of CKB's built-in type id script, which is
+// "TYPE_ID" in ASCII.
+var TypeIDCodeHash = Hash{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x49, 0x44}
+
+// CalculateTypeID returns type id args for the output at outputIndex of the
+// transaction creating it, firstInput is the first input of the transaction.
+func CalculateTypeID(firstInput CellInput, outputIndex uint64) ([]byte, error) {
+	h, err := newBlake2b()
+	if err != nil {
+		return nil, err
+	}
+	err = firstInput.SerializeToCore(h)
+	if err != nil {
+		return nil, err
+	}
+	var index [8]byte
+	binary.LittleEndian.PutUint64(index[:], outputIndex)
+	h.Write(index[:])
+	return h.Sum(nil), nil
+}
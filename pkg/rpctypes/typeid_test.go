@@ -0,0 +1,20 @@
+package rpctypes
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestCalculateTypeID(t *testing.T) {
+	input := CellInput{
+		PreviousOutput: OutPoint{Index: 2},
+	}
+	input.PreviousOutput.TxHash[0] = 1
+	typeID, err := CalculateTypeID(input, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(typeID) != "77bce34aa5f28d69278a23458b6652a6b90fccc69c1a49e2c11f0b679f18b1f2" {
+		t.Errorf("Invalid type id: %x", typeID)
+	}
+}
@@ -0,0 +1,210 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+
+	scryptR     = 8
+	scryptDKLen = 32
+)
+
+type encryptedKey struct {
+	LockArg rpctypes.Raw `json:"lock_arg"`
+	Crypto  struct {
+		Cipher       string       `json:"cipher"`
+		CipherText   rpctypes.Raw `json:"ciphertext"`
+		CipherParams struct {
+			IV rpctypes.Raw `json:"iv"`
+		} `json:"cipherparams"`
+		KDF       string `json:"kdf"`
+		KDFParams struct {
+			N     int          `json:"n"`
+			R     int          `json:"r"`
+			P     int          `json:"p"`
+			DKLen int          `json:"dklen"`
+			Salt  rpctypes.Raw `json:"salt"`
+		} `json:"kdfparams"`
+		MAC rpctypes.Raw `json:"mac"`
+	} `json:"crypto"`
+}
+
+func aesCTR(key []byte, iv []byte, input []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]byte, len(input))
+	cipher.NewCTR(block, iv).XORKeyStream(output, input)
+	return output, nil
+}
+
+func keyMAC(derivedKey []byte, cipherText []byte) ([]byte, error) {
+	data := make([]byte, 0, 16+len(cipherText))
+	data = append(data, derivedKey[16:32]...)
+	data = append(data, cipherText...)
+	return rpctypes.CalculateHash(rpctypes.Raw(data))
+}
+
+// EncryptKey encrypts a key with password using scrypt and aes-128-ctr,
+// the mac uses CKB's blake2b hash.
+func EncryptKey(key *Key, password string, scryptN int, scryptP int) ([]byte, error) {
+	lockArg, err := key.Blake160()
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	privateKey := key.PrivateKey.D.Bytes()
+	paddedKey := make([]byte, 32)
+	copy(paddedKey[32-len(privateKey):], privateKey)
+	cipherText, err := aesCTR(derivedKey[:16], iv, paddedKey)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := keyMAC(derivedKey, cipherText)
+	if err != nil {
+		return nil, err
+	}
+	var k encryptedKey
+	k.LockArg = lockArg
+	k.Crypto.Cipher = "aes-128-ctr"
+	k.Crypto.CipherText = cipherText
+	k.Crypto.CipherParams.IV = iv
+	k.Crypto.KDF = "scrypt"
+	k.Crypto.KDFParams.N = scryptN
+	k.Crypto.KDFParams.R = scryptR
+	k.Crypto.KDFParams.P = scryptP
+	k.Crypto.KDFParams.DKLen = scryptDKLen
+	k.Crypto.KDFParams.Salt = salt
+	k.Crypto.MAC = mac
+	return json.MarshalIndent(k, "", "  ")
+}
+
+func DecryptKey(data []byte, password string) (*Key, error) {
+	var k encryptedKey
+	err := json.Unmarshal(data, &k)
+	if err != nil {
+		return nil, err
+	}
+	if k.Crypto.Cipher != "aes-128-ctr" || k.Crypto.KDF != "scrypt" {
+		return nil, fmt.Errorf("Unsupported cipher %s or kdf %s!", k.Crypto.Cipher, k.Crypto.KDF)
+	}
+	params := k.Crypto.KDFParams
+	if params.DKLen != scryptDKLen {
+		return nil, fmt.Errorf("Invalid derived key length: %d", params.DKLen)
+	}
+	derivedKey, err := scrypt.Key([]byte(password), params.Salt, params.N, params.R, params.P, params.DKLen)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := keyMAC(derivedKey, k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(mac, k.Crypto.MAC) {
+		return nil, fmt.Errorf("Invalid password for key %x!", []byte(k.LockArg))
+	}
+	privateKey, err := aesCTR(derivedKey[:16], k.Crypto.CipherParams.IV, k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	key, err := KeyFromBytes(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	lockArg, err := key.Blake160()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(lockArg, k.LockArg) {
+		return nil, fmt.Errorf("Decrypted key does not match lock arg %x!", []byte(k.LockArg))
+	}
+	return key, nil
+}
+
+// Keystore keeps each encrypted key as a JSON file named by its lock arg
+// in a directory.
+type Keystore struct {
+	Dir     string
+	ScryptN int
+	ScryptP int
+}
+
+func NewKeystore(dir string) *Keystore {
+	return &Keystore{
+		Dir:     dir,
+		ScryptN: StandardScryptN,
+		ScryptP: StandardScryptP,
+	}
+}
+
+func (k *Keystore) Import(key *Key, password string) (string, error) {
+	data, err := EncryptKey(key, password, k.ScryptN, k.ScryptP)
+	if err != nil {
+		return "", err
+	}
+	lockArg, err := key.Blake160()
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(k.Dir, 0700)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(k.Dir, hex.EncodeToString(lockArg)+".json")
+	return path, ioutil.WriteFile(path, data, 0600)
+}
+
+// Unlock decrypts all keys in the keystore with the same password, keys
+// failing to decrypt are skipped and returned in failures by file name.
+func (k *Keystore) Unlock(password string) (keys []*Key, failures map[string]error, err error) {
+	files, err := ioutil.ReadDir(k.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys = make([]*Key, 0)
+	failures = make(map[string]error)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(k.Dir, file.Name()))
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := DecryptKey(data, password)
+		if err != nil {
+			failures[file.Name()] = err
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, failures, nil
+}
@@ -0,0 +1,76 @@
+package signer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeystore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keystore := NewKeystore(dir)
+	keystore.ScryptN = 1 << 10
+
+	key := testKey(t)
+	_, err = keystore.Import(key, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, failures, err := keystore.Unlock("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || !bytes.Equal(keys[0].PrivateKey.Serialize(), key.PrivateKey.Serialize()) {
+		t.Errorf("Invalid unlocked keys!")
+	}
+	if len(failures) != 0 {
+		t.Errorf("Unexpected failures: %v", failures)
+	}
+	keys, failures, err = keystore.Unlock("wrong password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 || len(failures) != 1 {
+		t.Errorf("Wrong password is accepted!")
+	}
+}
+
+func TestKeystoreSkipsFailedKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keystore := NewKeystore(dir)
+	keystore.ScryptN = 1 << 10
+
+	key := testKey(t)
+	_, err = keystore.Import(key, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPath, err := keystore.Import(other, "other password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, failures, err := keystore.Unlock("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || !bytes.Equal(keys[0].PrivateKey.Serialize(), key.PrivateKey.Serialize()) {
+		t.Errorf("Invalid unlocked keys!")
+	}
+	if _, found := failures[filepath.Base(otherPath)]; !found || len(failures) != 1 {
+		t.Errorf("Invalid failures: %v", failures)
+	}
+}
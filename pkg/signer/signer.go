@@ -0,0 +1,137 @@
+package signer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/xxuejie/animagus/pkg/address"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+type Key struct {
+	PrivateKey *btcec.PrivateKey
+}
+
+func NewKey() (*Key, error) {
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	return &Key{PrivateKey: privateKey}, nil
+}
+
+func KeyFromBytes(b []byte) (*Key, error) {
+	if len(b) != 32 {
+		return nil, fmt.Errorf("Private key should be exactly 32 bytes!")
+	}
+	privateKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), b)
+	return &Key{PrivateKey: privateKey}, nil
+}
+
+// Blake160 returns the first 20 bytes of the hash of the compressed public
+// key, which is the args of secp256k1_blake160_sighash_all lock.
+func (k *Key) Blake160() ([]byte, error) {
+	h, err := rpctypes.CalculateHash(rpctypes.Raw(k.PrivateKey.PubKey().SerializeCompressed()))
+	if err != nil {
+		return nil, err
+	}
+	return h[:20], nil
+}
+
+// Sign generates a recoverable signature in the layout CKB expects:
+// r, s, then the recovery id.
+func (k *Key) Sign(message []byte) ([]byte, error) {
+	if len(message) != 32 {
+		return nil, fmt.Errorf("Message to sign should be exactly 32 bytes!")
+	}
+	compact, err := btcec.SignCompact(btcec.S256(), k.PrivateKey, message, true)
+	if err != nil {
+		return nil, err
+	}
+	signature := make([]byte, 65)
+	copy(signature, compact[1:])
+	signature[64] = (compact[0] - 27) & 3
+	return signature, nil
+}
+
+type Signer struct {
+	keys []*Key
+}
+
+func NewSigner(keys []*Key) *Signer {
+	return &Signer{
+		keys: keys,
+	}
+}
+
+func (s *Signer) findKey(lock rpctypes.Script) (*Key, error) {
+	if lock.CodeHash != address.Secp256k1Blake160TypeHash ||
+		lock.HashType != rpctypes.Type {
+		return nil, nil
+	}
+	for _, key := range s.keys {
+		blake160, err := key.Blake160()
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(blake160, lock.Args) {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+// SignTransaction fills the lock field of the first witness in each input
+// group locked by a key held in the signer, locks contains the lock script
+// of each input. It returns the number of groups signed, groups locked by
+// other locks are left untouched.
+func (s *Signer) SignTransaction(tx *rpctypes.Transaction, locks []rpctypes.Script) (int, error) {
+	if len(locks) != len(tx.Inputs) {
+		return 0, fmt.Errorf("Expected %d input locks, got %d!", len(tx.Inputs), len(locks))
+	}
+	for len(tx.Witnesses) < len(tx.Inputs) {
+		tx.Witnesses = append(tx.Witnesses, rpctypes.Bytes{})
+	}
+	groups := make([][]int, 0)
+	for i, lock := range locks {
+		found := false
+		for j, group := range groups {
+			if locks[group[0]].CodeHash == lock.CodeHash &&
+				locks[group[0]].HashType == lock.HashType &&
+				bytes.Equal(locks[group[0]].Args, lock.Args) {
+				groups[j] = append(group, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, []int{i})
+		}
+	}
+	signed := 0
+	for _, group := range groups {
+		key, err := s.findKey(locks[group[0]])
+		if err != nil {
+			return signed, err
+		}
+		if key == nil {
+			continue
+		}
+		message, err := tx.SigningMessage(group, rpctypes.Secp256k1SignatureSize)
+		if err != nil {
+			return signed, err
+		}
+		signature, err := key.Sign(message)
+		if err != nil {
+			return signed, err
+		}
+		witness, err := rpctypes.WitnessWithLock(tx.Witnesses[group[0]], signature)
+		if err != nil {
+			return signed, err
+		}
+		tx.Witnesses[group[0]] = witness
+		signed++
+	}
+	return signed, nil
+}
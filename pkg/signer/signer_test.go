@@ -0,0 +1,93 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/xxuejie/animagus/pkg/address"
+	"github.com/xxuejie/animagus/pkg/coretypes"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+func testKey(t *testing.T) *Key {
+	b, _ := hex.DecodeString("d00c06bfd800d27397002dca6fb0993d5ba6399b4238b2f29ee9deb97593d2bc")
+	key, err := KeyFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestBlake160(t *testing.T) {
+	blake160, err := testKey(t).Blake160()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(blake160) != "c8328aabcd9b9e8e64fbc566c4385c3bdeb219d7" {
+		t.Errorf("Invalid blake160: %x", blake160)
+	}
+}
+
+func TestSignTransaction(t *testing.T) {
+	key := testKey(t)
+	blake160, _ := key.Blake160()
+	lock := rpctypes.Script{
+		CodeHash: address.Secp256k1Blake160TypeHash,
+		HashType: rpctypes.Type,
+		Args:     blake160,
+	}
+	otherLock := rpctypes.Script{
+		CodeHash: address.Secp256k1Blake160TypeHash,
+		HashType: rpctypes.Type,
+		Args:     make([]byte, 20),
+	}
+	tx := rpctypes.Transaction{
+		RawTransaction: rpctypes.RawTransaction{
+			CellDeps:   []rpctypes.CellDep{},
+			HeaderDeps: []rpctypes.Hash{},
+			Inputs: []rpctypes.CellInput{
+				rpctypes.CellInput{PreviousOutput: rpctypes.OutPoint{Index: 0}},
+				rpctypes.CellInput{PreviousOutput: rpctypes.OutPoint{Index: 1}},
+				rpctypes.CellInput{PreviousOutput: rpctypes.OutPoint{Index: 2}},
+			},
+			Outputs: []rpctypes.CellOutput{
+				rpctypes.CellOutput{Capacity: 100 * rpctypes.Uint64(rpctypes.ShannonsPerByte), Lock: lock},
+			},
+			OutputsData: []rpctypes.Bytes{rpctypes.Bytes{}},
+		},
+	}
+	message, err := tx.SigningMessage([]int{0, 2}, rpctypes.Secp256k1SignatureSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := NewSigner([]*Key{key})
+	signed, err := signer.SignTransaction(&tx, []rpctypes.Script{lock, otherLock, lock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed != 1 {
+		t.Errorf("Invalid number of signed groups: %d", signed)
+	}
+	if len(tx.Witnesses[1]) != 0 || len(tx.Witnesses[2]) != 0 {
+		t.Errorf("Witnesses not belonging to the first input of the group are changed!")
+	}
+	witness := coretypes.WitnessArgs(tx.Witnesses[0])
+	if !witness.Verify(false) || !witness.HasLock() {
+		t.Fatalf("Invalid signed witness: %x", []byte(tx.Witnesses[0]))
+	}
+	signature := witness.Lock().Value()
+	if len(signature) != 65 {
+		t.Fatalf("Invalid signature length: %d", len(signature))
+	}
+	compact := append([]byte{27 + 4 + signature[64]}, signature[:64]...)
+	pubkey, _, err := btcec.RecoverCompact(btcec.S256(), compact, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pubkey.SerializeCompressed(), key.PrivateKey.PubKey().SerializeCompressed()) {
+		t.Errorf("Signature is not signed by the key!")
+	}
+}
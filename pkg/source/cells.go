@@ -0,0 +1,127 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+// StoredCellSource is implemented by sources returning blocks without
+// resolved previous outputs. For those sources, the indexer keeps all live
+// cells in the store under CellKey, and resolves previous outputs from
+// them via ResolveInputs.
+type StoredCellSource interface {
+	Source
+	// PreviousOutput fetches a cell missing from the store, such as one
+	// created before the indexer's start block. It returns nil when the
+	// cell does not exist.
+	PreviousOutput(outPoint rpctypes.OutPoint) (*StoredCell, error)
+}
+
+type StoredCell struct {
+	Output    rpctypes.CellOutput `json:"output"`
+	Data      rpctypes.Raw        `json:"data"`
+	BlockHash rpctypes.Hash       `json:"block_hash"`
+}
+
+func CellKey(outPoint rpctypes.OutPoint) string {
+	return fmt.Sprintf("CELL:%x:%d", outPoint.TxHash[:], outPoint.Index)
+}
+
+func EncodeCell(cell StoredCell) ([]byte, error) {
+	return json.Marshal(cell)
+}
+
+func DecodeCell(data []byte) (*StoredCell, error) {
+	cell := &StoredCell{}
+	err := json.Unmarshal(data, cell)
+	if err != nil {
+		return nil, err
+	}
+	return cell, nil
+}
+
+func IsCellbaseInput(input rpctypes.CellInput) bool {
+	return input.PreviousOutput.TxHash == rpctypes.Hash{} &&
+		input.PreviousOutput.Index == 0xffffffff
+}
+
+// ResolveInputs fills previous outputs of all non-cellbase inputs in block,
+// using cells created earlier in the same block, or cells returned by get.
+// Cells missing from get are fetched via fetch when it is not nil.
+func ResolveInputs(block *rpctypes.BlockView, get func(key string) ([]byte, error),
+	fetch func(outPoint rpctypes.OutPoint) (*StoredCell, error)) error {
+	created := make(map[string]StoredCell)
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		for j := range tx.Inputs {
+			input := &tx.Inputs[j]
+			if IsCellbaseInput(*input) || input.PreviousOutput.GraphqlCell != nil {
+				continue
+			}
+			key := CellKey(input.PreviousOutput)
+			cell, found := created[key]
+			if found {
+				delete(created, key)
+			} else {
+				c, err := storedCell(input.PreviousOutput, get, fetch)
+				if err != nil {
+					return err
+				}
+				if c == nil {
+					return fmt.Errorf("Input %x:%d of transaction %x cannot be resolved!",
+						input.PreviousOutput.TxHash[:], input.PreviousOutput.Index, tx.Hash[:])
+				}
+				cell = *c
+			}
+			content := cell.Data
+			input.PreviousOutput.GraphqlCell = &cell.Output
+			input.PreviousOutput.GraphqlCellData = &rpctypes.GraphqlBytes{Content: &content}
+		}
+		err := fillCellsData(tx)
+		if err != nil {
+			return err
+		}
+		for j, output := range tx.Outputs {
+			created[CellKey(rpctypes.OutPoint{TxHash: tx.Hash, Index: rpctypes.Uint32(j)})] = StoredCell{
+				Output:    output,
+				Data:      *tx.RawTransaction.GraphqlCellsData[j].Content,
+				BlockHash: block.Header.Hash,
+			}
+		}
+	}
+	return nil
+}
+
+func storedCell(outPoint rpctypes.OutPoint, get func(key string) ([]byte, error),
+	fetch func(outPoint rpctypes.OutPoint) (*StoredCell, error)) (*StoredCell, error) {
+	data, err := get(CellKey(outPoint))
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		return DecodeCell(data)
+	}
+	if fetch == nil {
+		return nil, nil
+	}
+	return fetch(rpctypes.OutPoint{TxHash: outPoint.TxHash, Index: outPoint.Index})
+}
+
+// fillCellsData fills GraphqlCellsData from outputs data when it is missing.
+func fillCellsData(tx *rpctypes.TransactionView) error {
+	if len(tx.RawTransaction.GraphqlCellsData) == len(tx.Outputs) {
+		return nil
+	}
+	if len(tx.OutputsData) != len(tx.Outputs) {
+		return fmt.Errorf("Transaction %x has %d outputs but %d outputs data!",
+			tx.Hash[:], len(tx.Outputs), len(tx.OutputsData))
+	}
+	tx.RawTransaction.GraphqlCellsData = make([]rpctypes.GraphqlBytes, len(tx.OutputsData))
+	for j, data := range tx.OutputsData {
+		content := rpctypes.Raw(data)
+		tx.RawTransaction.GraphqlCellsData[j] = rpctypes.GraphqlBytes{Content: &content}
+	}
+	return nil
+}
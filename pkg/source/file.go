@@ -0,0 +1,121 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+// FileSource replays BlockView documents loaded from a directory of JSON
+// files (sorted by file name), or from a single file containing one or more
+// JSON documents such as a JSONL file.
+//
+// Documents are applied in order as the indexer asks for new blocks, a
+// document with a block number not greater than current tip replaces the
+// chain starting from that block, so reorgs can be replayed as well.
+type FileSource struct {
+	mutex     sync.Mutex
+	documents []rpctypes.BlockView
+	applied   int
+	chain     *MemorySource
+}
+
+func NewFileSource(path string) (*FileSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var documents []rpctypes.BlockView
+	if info.IsDir() {
+		files, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			if !file.IsDir() && (strings.HasSuffix(file.Name(), ".json") ||
+				strings.HasSuffix(file.Name(), ".jsonl")) {
+				names = append(names, file.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			blocks, err := loadBlocks(filepath.Join(path, name))
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, blocks...)
+		}
+	} else {
+		documents, err = loadBlocks(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("No blocks found in %s!", path)
+	}
+	return &FileSource{
+		documents: documents,
+		chain:     NewMemorySource(),
+	}, nil
+}
+
+func loadBlocks(path string) ([]rpctypes.BlockView, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var blocks []rpctypes.BlockView
+	decoder := json.NewDecoder(file)
+	for {
+		var block rpctypes.BlockView
+		err = decoder.Decode(&block)
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error loading %s: %s", path, err)
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+// FirstBlockNumber returns the number of the first block to replay.
+func (s *FileSource) FirstBlockNumber() uint64 {
+	return uint64(s.documents[0].Header.Number)
+}
+
+func (s *FileSource) Block(blockNumber uint64) (*rpctypes.BlockView, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for s.applied < len(s.documents) {
+		tip := s.chain.Tip()
+		if tip != nil && uint64(tip.Header.Number) >= blockNumber {
+			break
+		}
+		block := s.documents[s.applied]
+		s.chain.Rollback(uint64(block.Header.Number))
+		err := s.chain.AddBlock(block)
+		if err != nil {
+			return nil, fmt.Errorf("Error replaying block %d: %s", s.applied, err)
+		}
+		s.applied++
+	}
+	return s.chain.Block(blockNumber)
+}
+
+func (s *FileSource) Cells(outPoints []rpctypes.OutPoint) ([]*rpctypes.OutPoint, error) {
+	return s.chain.Cells(outPoints)
+}
@@ -0,0 +1,102 @@
+package source
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+func TestFileSourceSingleBlock(t *testing.T) {
+	s, err := NewFileSource(filepath.Join("..", "rpctypes", "testdata", "block2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.FirstBlockNumber() != 15079 {
+		t.Fatalf("Invalid first block number: %d", s.FirstBlockNumber())
+	}
+	block, err := s.Block(15078)
+	if err != nil || block != nil {
+		t.Errorf("Block before first block should be nil: %v, error: %v", block, err)
+	}
+	block, err = s.Block(15079)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block == nil || len(block.Transactions[0].GraphqlCellsData) != len(block.Transactions[0].Outputs) {
+		t.Fatalf("Invalid block: %v", block)
+	}
+	block, err = s.Block(15080)
+	if err != nil || block != nil {
+		t.Errorf("Block after last block should be nil: %v, error: %v", block, err)
+	}
+}
+
+func TestFileSourceReplay(t *testing.T) {
+	chain := NewMemorySource()
+	var documents []rpctypes.BlockView
+	appendBlock := func(capacity uint64) {
+		block, err := chain.AppendTransactions(rpctypes.Transaction{
+			RawTransaction: rpctypes.RawTransaction{
+				Outputs:     []rpctypes.CellOutput{rpctypes.CellOutput{Capacity: rpctypes.Uint64(capacity)}},
+				OutputsData: []rpctypes.Bytes{rpctypes.Bytes{}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		documents = append(documents, *block)
+	}
+	appendBlock(1)
+	appendBlock(2)
+	appendBlock(3)
+	chain.Rollback(1)
+	appendBlock(4)
+	appendBlock(5)
+	appendBlock(6)
+
+	dir, err := ioutil.TempDir("", "animagus-blocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, document := range documents {
+		data, err := json.Marshal(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, string('a'+rune(i))+".json"), data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := NewFileSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []struct {
+		number   uint64
+		document int
+	}{
+		{0, 0}, {1, 1}, {2, 2}, {3, 5}, {2, 4}, {1, 3}, {2, 4}, {3, 5}, {4, -1},
+	}
+	for _, request := range requests {
+		block, err := s.Block(request.number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if request.document == -1 {
+			if block != nil {
+				t.Errorf("Block %d should be nil!", request.number)
+			}
+			continue
+		}
+		if block == nil || block.Header.Hash != documents[request.document].Header.Hash {
+			t.Errorf("Invalid block %d, expected document %d", request.number, request.document)
+		}
+	}
+}
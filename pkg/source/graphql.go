@@ -0,0 +1,179 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/machinebox/graphql"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+// GraphqlSource reads chain data from ckb-graphql-server.
+type GraphqlSource struct {
+	client *graphql.Client
+}
+
+func NewGraphqlSource(graphqlUrl string) (*GraphqlSource, error) {
+	client := graphql.NewClient(graphqlUrl)
+	// client.Log = func(s string) {
+	// 	fmt.Printf("GraphQL log: %s\n", s)
+	// }
+	// Test GraphQL query
+	err := client.Run(context.Background(), graphql.NewRequest(`
+query {
+  apiVersion
+}
+`), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &GraphqlSource{client: client}, nil
+}
+
+type getTipHeaderResponse struct {
+	GetTipHeader *struct {
+		Number rpctypes.Uint64 `json:"number"`
+	}
+}
+
+func (s *GraphqlSource) TipBlockNumber() (uint64, error) {
+	var response getTipHeaderResponse
+	err := s.client.Run(context.Background(), graphql.NewRequest(`
+query {
+  getTipHeader {
+    number
+  }
+}
+`), &response)
+	if err != nil {
+		return 0, err
+	}
+	if response.GetTipHeader == nil {
+		return 0, fmt.Errorf("Tip header is missing!")
+	}
+	return uint64(response.GetTipHeader.Number), nil
+}
+
+type getBlockResponse struct {
+	GetBlock *rpctypes.BlockView
+}
+
+func (s *GraphqlSource) Block(blockNumber uint64) (*rpctypes.BlockView, error) {
+	req := graphql.NewRequest(`
+query($blockNumber: String) {
+  getBlock(number: $blockNumber) {
+    header {
+      parent_hash
+      hash
+      number
+    }
+    transactions {
+      hash
+      inputs {
+        previous_output {
+          cell {
+            capacity
+            lock {
+              code_hash
+              hash_type
+              args
+            }
+            type {
+              code_hash
+              hash_type
+              args
+            }
+          }
+          cell_data {
+            content
+          }
+          tx_hash
+          index
+        }
+      }
+      outputs {
+        capacity
+        lock {
+          code_hash
+          hash_type
+          args
+        }
+        type {
+          code_hash
+          hash_type
+          args
+        }
+      }
+      cells_data {
+        content
+      }
+    }
+  }
+}
+`)
+	req.Var("blockNumber", rpctypes.Uint64(blockNumber).EncodeToString())
+	var response getBlockResponse
+	err := s.client.Run(context.Background(), req, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.GetBlock, nil
+}
+
+type getCellsResponse struct {
+	GetCells []*rpctypes.OutPoint
+}
+
+func (s *GraphqlSource) Cells(outPoints []rpctypes.OutPoint) ([]*rpctypes.OutPoint, error) {
+	req := graphql.NewRequest(fmt.Sprintf(`
+query {
+  getCells(outPoints: %s, skipMissing: true) {
+    cell {
+      capacity
+      lock {
+        code_hash
+        hash_type
+        args
+      }
+      type {
+        code_hash
+        hash_type
+        args
+      }
+    }
+    cell_data {
+      content
+    }
+    tx_hash
+    index
+    header {
+      compact_target
+      parent_hash
+      timestamp
+      number
+      epoch
+      transactions_root
+      proposals_hash
+      uncles_hash
+      dao
+      nonce
+    }
+  }
+}
+`, assembleQueryString(outPoints)))
+	var response getCellsResponse
+	err := s.client.Run(context.Background(), req, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.GetCells, nil
+}
+
+func assembleQueryString(outPoints []rpctypes.OutPoint) string {
+	pieces := make([]string, len(outPoints))
+	for i, outPoint := range outPoints {
+		pieces[i] = fmt.Sprintf("{txHash: \"0x%x\", index: \"0x%x\"}", outPoint.TxHash[:], uint32(outPoint.Index))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(pieces, ", "))
+}
@@ -0,0 +1,54 @@
+package source
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestGraphqlServer replies queries containing a key of responses with
+// the data of the key.
+func newTestGraphqlServer(t *testing.T, responses map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Query string `json:"query"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for key, data := range responses {
+			if strings.Contains(request.Query, key) {
+				json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []interface{}{map[string]string{"message": "Unknown query"}},
+		})
+	}))
+}
+
+func TestGraphqlTipBlockNumber(t *testing.T) {
+	server := newTestGraphqlServer(t, map[string]interface{}{
+		"apiVersion": map[string]string{"apiVersion": "0.1.0"},
+		"getTipHeader": map[string]interface{}{
+			"getTipHeader": map[string]string{"number": "0x2a"},
+		},
+	})
+	defer server.Close()
+
+	s, err := NewGraphqlSource(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tipSource TipSource = s
+	tip, err := tipSource.TipBlockNumber()
+	if err != nil || tip != 42 {
+		t.Errorf("Invalid tip: %d, error: %v", tip, err)
+	}
+}
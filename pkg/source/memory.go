@@ -0,0 +1,209 @@
+package source
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+// MemorySource serves blocks kept in memory, it is mainly useful in tests.
+type MemorySource struct {
+	mutex sync.Mutex
+	// Block number of the first block, it is set by the first added block.
+	start  uint64
+	blocks []rpctypes.BlockView
+}
+
+func NewMemorySource() *MemorySource {
+	return &MemorySource{}
+}
+
+// AddBlock appends a block to the chain. Previous outputs of inputs that are
+// not resolved yet are resolved from cells created in earlier blocks.
+func (s *MemorySource) AddBlock(block rpctypes.BlockView) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.blocks) == 0 {
+		s.start = uint64(block.Header.Number)
+	}
+	expected := s.start + uint64(len(s.blocks))
+	if uint64(block.Header.Number) != expected {
+		return fmt.Errorf("Invalid block number: %d, expected: %d", block.Header.Number, expected)
+	}
+	if len(s.blocks) > 0 && block.Header.ParentHash != s.blocks[len(s.blocks)-1].Header.Hash {
+		return fmt.Errorf("Block %x does not extend current tip!", block.Header.Hash[:])
+	}
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		err := fillCellsData(tx)
+		if err != nil {
+			return err
+		}
+		for j := range tx.RawTransaction.Inputs {
+			input := &tx.RawTransaction.Inputs[j]
+			if input.PreviousOutput.GraphqlCell != nil {
+				continue
+			}
+			cell := s.findCell(input.PreviousOutput, block)
+			if cell != nil {
+				input.PreviousOutput.GraphqlCell = cell.GraphqlCell
+				input.PreviousOutput.GraphqlCellData = cell.GraphqlCellData
+			}
+		}
+	}
+	s.blocks = append(s.blocks, block)
+	return nil
+}
+
+// AppendTransactions builds a new block containing txs on top of current tip
+// and adds it. Transaction hashes and outputs data are filled here, the
+// transactions root of the block is simplified to the hash of all transaction
+// hashes.
+func (s *MemorySource) AppendTransactions(txs ...rpctypes.Transaction) (*rpctypes.BlockView, error) {
+	header := rpctypes.Header{
+		RawHeader: rpctypes.RawHeader{
+			Dao: make([]byte, 32),
+		},
+		Nonce: rpctypes.Uint128{V: big.NewInt(0)},
+	}
+	tip := s.Tip()
+	if tip != nil {
+		header.Number = tip.Header.Number + 1
+		header.ParentHash = tip.Header.Hash
+	}
+	block := rpctypes.BlockView{
+		Transactions: make([]rpctypes.TransactionView, len(txs)),
+	}
+	var txHashes []byte
+	for i, tx := range txs {
+		txHash, err := rpctypes.CalculateHash(tx.RawTransaction)
+		if err != nil {
+			return nil, err
+		}
+		txHashes = append(txHashes, txHash...)
+		if tx.GraphqlCellsData == nil {
+			tx.GraphqlCellsData = make([]rpctypes.GraphqlBytes, len(tx.OutputsData))
+			for j, data := range tx.OutputsData {
+				content := rpctypes.Raw(data)
+				tx.GraphqlCellsData[j] = rpctypes.GraphqlBytes{Content: &content}
+			}
+		}
+		block.Transactions[i].Transaction = tx
+		copy(block.Transactions[i].Hash[:], txHash)
+	}
+	transactionsRoot, err := rpctypes.CalculateHash(rpctypes.Raw(txHashes))
+	if err != nil {
+		return nil, err
+	}
+	copy(header.TransactionsRoot[:], transactionsRoot)
+	hash, err := rpctypes.CalculateHash(header)
+	if err != nil {
+		return nil, err
+	}
+	block.Header.Header = header
+	copy(block.Header.Hash[:], hash)
+	err = s.AddBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Rollback removes all blocks starting from blockNumber, so a fork can be
+// built on top of the remaining blocks.
+func (s *MemorySource) Rollback(blockNumber uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if blockNumber <= s.start {
+		s.blocks = nil
+	} else if blockNumber-s.start < uint64(len(s.blocks)) {
+		s.blocks = s.blocks[:blockNumber-s.start]
+	}
+}
+
+func (s *MemorySource) Tip() *rpctypes.BlockView {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.blocks) == 0 {
+		return nil
+	}
+	block := s.blocks[len(s.blocks)-1]
+	return &block
+}
+
+// TipBlockNumber returns 0 when there are no blocks yet.
+func (s *MemorySource) TipBlockNumber() (uint64, error) {
+	tip := s.Tip()
+	if tip == nil {
+		return 0, nil
+	}
+	return uint64(tip.Header.Number), nil
+}
+
+func (s *MemorySource) Block(blockNumber uint64) (*rpctypes.BlockView, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if blockNumber < s.start || blockNumber-s.start >= uint64(len(s.blocks)) {
+		return nil, nil
+	}
+	block := s.blocks[blockNumber-s.start]
+	return &block, nil
+}
+
+func (s *MemorySource) Cells(outPoints []rpctypes.OutPoint) ([]*rpctypes.OutPoint, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	results := make([]*rpctypes.OutPoint, 0, len(outPoints))
+	for _, outPoint := range outPoints {
+		if s.spent(outPoint) {
+			continue
+		}
+		cell := s.findCell(outPoint, rpctypes.BlockView{})
+		if cell != nil {
+			results = append(results, cell)
+		}
+	}
+	return results, nil
+}
+
+// findCell looks for the cell in current chain as well as in pendingBlock.
+func (s *MemorySource) findCell(outPoint rpctypes.OutPoint, pendingBlock rpctypes.BlockView) *rpctypes.OutPoint {
+	blocks := append(s.blocks[:len(s.blocks):len(s.blocks)], pendingBlock)
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			if tx.Hash != outPoint.TxHash || int(outPoint.Index) >= len(tx.RawTransaction.Outputs) {
+				continue
+			}
+			header := block.Header.Header
+			return &rpctypes.OutPoint{
+				TxHash:          outPoint.TxHash,
+				Index:           outPoint.Index,
+				GraphqlCell:     &tx.RawTransaction.Outputs[outPoint.Index],
+				GraphqlCellData: &tx.RawTransaction.GraphqlCellsData[outPoint.Index],
+				GraphqlHeader:   &header,
+			}
+		}
+	}
+	return nil
+}
+
+func (s *MemorySource) spent(outPoint rpctypes.OutPoint) bool {
+	for _, block := range s.blocks {
+		for _, tx := range block.Transactions {
+			for _, input := range tx.RawTransaction.Inputs {
+				if input.PreviousOutput.TxHash == outPoint.TxHash &&
+					input.PreviousOutput.Index == outPoint.Index {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+// RpcSource reads chain data from CKB's JSON-RPC. CKB does not provide
+// previous outputs of inputs in blocks, so live cells kept in the store by
+// the indexer are used to resolve them, as well as to serve Cells. Cells
+// missing from the store are fetched via get_transaction.
+type RpcSource struct {
+	url    string
+	client *http.Client
+	store  store.Store
+	id     uint64
+}
+
+func NewRpcSource(url string, s store.Store) (*RpcSource, error) {
+	source := &RpcSource{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+		store:  s,
+	}
+	// Test RPC connection
+	_, err := source.TipBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+type rpcRequest struct {
+	Id      uint64        `json:"id"`
+	Jsonrpc string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	Id     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// call returns false when result is null.
+func (s *RpcSource) call(result interface{}, method string, params ...interface{}) (bool, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(rpcRequest{
+		Id:      atomic.AddUint64(&s.id, 1),
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return false, err
+	}
+	httpResponse, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("RPC %s returns HTTP status %d", method, httpResponse.StatusCode)
+	}
+	var response rpcResponse
+	err = json.NewDecoder(httpResponse.Body).Decode(&response)
+	if err != nil {
+		return false, err
+	}
+	if response.Error != nil {
+		return false, fmt.Errorf("RPC %s error %d: %s", method, response.Error.Code, response.Error.Message)
+	}
+	if len(response.Result) == 0 || string(response.Result) == "null" {
+		return false, nil
+	}
+	return true, json.Unmarshal(response.Result, result)
+}
+
+func (s *RpcSource) TipBlockNumber() (uint64, error) {
+	var number rpctypes.Uint64
+	found, err := s.call(&number, "get_tip_block_number")
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("Tip block number is missing!")
+	}
+	return uint64(number), nil
+}
+
+func (s *RpcSource) Block(blockNumber uint64) (*rpctypes.BlockView, error) {
+	block := &rpctypes.BlockView{}
+	found, err := s.call(block, "get_block_by_number", rpctypes.Uint64(blockNumber).EncodeToString())
+	if err != nil || !found {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (s *RpcSource) Cells(outPoints []rpctypes.OutPoint) ([]*rpctypes.OutPoint, error) {
+	headers := make(map[rpctypes.Hash]*rpctypes.Header)
+	results := make([]*rpctypes.OutPoint, 0, len(outPoints))
+	for _, outPoint := range outPoints {
+		data, err := s.store.Get(CellKey(outPoint))
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		cell, err := DecodeCell(data)
+		if err != nil {
+			return nil, err
+		}
+		header, found := headers[cell.BlockHash]
+		if !found {
+			headerView := &rpctypes.HeaderView{}
+			found, err = s.call(headerView, "get_header", cell.BlockHash)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				return nil, fmt.Errorf("Header %x is missing!", cell.BlockHash[:])
+			}
+			header = &headerView.Header
+			headers[cell.BlockHash] = header
+		}
+		content := cell.Data
+		results = append(results, &rpctypes.OutPoint{
+			TxHash:          outPoint.TxHash,
+			Index:           outPoint.Index,
+			GraphqlCell:     &cell.Output,
+			GraphqlCellData: &rpctypes.GraphqlBytes{Content: &content},
+			GraphqlHeader:   header,
+		})
+	}
+	return results, nil
+}
+
+type rpcTransactionWithStatus struct {
+	Transaction rpctypes.TransactionView `json:"transaction"`
+	TxStatus    struct {
+		BlockHash *rpctypes.Hash `json:"block_hash"`
+	} `json:"tx_status"`
+}
+
+func (s *RpcSource) PreviousOutput(outPoint rpctypes.OutPoint) (*StoredCell, error) {
+	var tx rpcTransactionWithStatus
+	found, err := s.call(&tx, "get_transaction", outPoint.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	if !found || tx.TxStatus.BlockHash == nil {
+		return nil, nil
+	}
+	index := int(outPoint.Index)
+	if index >= len(tx.Transaction.Outputs) || index >= len(tx.Transaction.OutputsData) {
+		return nil, nil
+	}
+	return &StoredCell{
+		Output:    tx.Transaction.Outputs[index],
+		Data:      rpctypes.Raw(tx.Transaction.OutputsData[index]),
+		BlockHash: *tx.TxStatus.BlockHash,
+	}, nil
+}
@@ -0,0 +1,317 @@
+package source
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+	"github.com/xxuejie/animagus/pkg/store"
+)
+
+type testRpcHandler func(params []json.RawMessage) (interface{}, *rpcError)
+
+func newTestRpcServer(t *testing.T, handlers map[string]testRpcHandler) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Id     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response := map[string]interface{}{
+			"id":      request.Id,
+			"jsonrpc": "2.0",
+		}
+		handler, found := handlers[request.Method]
+		if !found {
+			response["error"] = &rpcError{Code: -32601, Message: "Method not found"}
+		} else {
+			result, rpcErr := handler(request.Params)
+			if rpcErr != nil {
+				response["error"] = rpcErr
+			} else {
+				response["result"] = result
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func testTransaction(inputs []rpctypes.OutPoint, capacity uint64, data []byte) rpctypes.TransactionView {
+	tx := rpctypes.TransactionView{}
+	if len(inputs) == 0 {
+		tx.Inputs = []rpctypes.CellInput{
+			rpctypes.CellInput{PreviousOutput: rpctypes.OutPoint{Index: 0xffffffff}},
+		}
+	}
+	for _, input := range inputs {
+		tx.Inputs = append(tx.Inputs, rpctypes.CellInput{PreviousOutput: input})
+	}
+	tx.Outputs = []rpctypes.CellOutput{
+		rpctypes.CellOutput{Capacity: rpctypes.Uint64(capacity)},
+	}
+	tx.OutputsData = []rpctypes.Bytes{data}
+	return tx
+}
+
+func TestRpcBlock(t *testing.T) {
+	block := rpctypes.BlockView{}
+	block.Header.Number = 1
+	block.Header.Hash[0] = 1
+	block.Header.Nonce = rpctypes.Uint128{V: big.NewInt(0)}
+	tx := testTransaction(nil, 1000, []byte{1})
+	tx.Hash[0] = 2
+	block.Transactions = []rpctypes.TransactionView{tx}
+
+	server := newTestRpcServer(t, map[string]testRpcHandler{
+		"get_tip_block_number": func(params []json.RawMessage) (interface{}, *rpcError) {
+			return "0x1", nil
+		},
+		"get_block_by_number": func(params []json.RawMessage) (interface{}, *rpcError) {
+			var number rpctypes.Uint64
+			err := json.Unmarshal(params[0], &number)
+			if err != nil {
+				return nil, &rpcError{Code: -32602, Message: err.Error()}
+			}
+			if number == 1 {
+				return block, nil
+			}
+			if number > 1 {
+				return nil, nil
+			}
+			return nil, &rpcError{Code: -1, Message: "Internal error"}
+		},
+	})
+	defer server.Close()
+
+	s, err := NewRpcSource(server.URL, store.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip, err := s.TipBlockNumber()
+	if err != nil || tip != 1 {
+		t.Fatalf("Invalid tip: %d, error: %v", tip, err)
+	}
+	b, err := s.Block(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Header.Hash != block.Header.Hash || len(b.Transactions) != 1 ||
+		b.Transactions[0].Hash != tx.Hash || b.Transactions[0].Outputs[0].Capacity != 1000 {
+		t.Errorf("Invalid block: %v", b)
+	}
+	b, err = s.Block(2)
+	if err != nil || b != nil {
+		t.Errorf("Missing block should be nil: %v, error: %v", b, err)
+	}
+	_, err = s.Block(0)
+	if err == nil {
+		t.Error("RPC error should be returned!")
+	}
+}
+
+func TestRpcCells(t *testing.T) {
+	header := rpctypes.HeaderView{}
+	header.Number = 5
+	header.Dao = make([]byte, 32)
+	header.Hash[0] = 5
+	header.Nonce = rpctypes.Uint128{V: big.NewInt(0)}
+	headerRequests := 0
+	server := newTestRpcServer(t, map[string]testRpcHandler{
+		"get_tip_block_number": func(params []json.RawMessage) (interface{}, *rpcError) {
+			return "0x5", nil
+		},
+		"get_header": func(params []json.RawMessage) (interface{}, *rpcError) {
+			headerRequests++
+			var hash rpctypes.Hash
+			err := json.Unmarshal(params[0], &hash)
+			if err != nil || hash != header.Hash {
+				return nil, nil
+			}
+			return header, nil
+		},
+	})
+	defer server.Close()
+
+	s := store.NewMemoryStore()
+	var live, dead rpctypes.OutPoint
+	live.TxHash[0] = 1
+	dead.TxHash[0] = 2
+	live2 := live
+	live2.Index = 1
+	batch := &store.Batch{}
+	for _, outPoint := range []rpctypes.OutPoint{live, live2} {
+		data, err := EncodeCell(StoredCell{
+			Output:    rpctypes.CellOutput{Capacity: 100},
+			Data:      rpctypes.Raw{1, 2},
+			BlockHash: header.Hash,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch.Set(CellKey(outPoint), data)
+	}
+	err := s.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpcSource, err := NewRpcSource(server.URL, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells, err := rpcSource.Cells([]rpctypes.OutPoint{live, dead, live2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cells) != 2 || cells[0].TxHash != live.TxHash || cells[1].Index != 1 {
+		t.Fatalf("Invalid cells: %v", cells)
+	}
+	if cells[0].GraphqlCell.Capacity != 100 || len(*cells[0].GraphqlCellData.Content) != 2 ||
+		cells[0].GraphqlHeader.Number != 5 {
+		t.Errorf("Invalid cell: %v", cells[0])
+	}
+	if headerRequests != 1 {
+		t.Errorf("Header should only be requested once, actual: %d", headerRequests)
+	}
+}
+
+func TestResolveInputs(t *testing.T) {
+	var stored rpctypes.OutPoint
+	stored.TxHash[0] = 1
+	data, err := EncodeCell(StoredCell{
+		Output: rpctypes.CellOutput{Capacity: 300},
+		Data:   rpctypes.Raw{3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(key string) ([]byte, error) {
+		if key == CellKey(stored) {
+			return data, nil
+		}
+		return nil, nil
+	}
+
+	tx1 := testTransaction([]rpctypes.OutPoint{stored}, 200, []byte{2})
+	tx1.Hash[0] = 2
+	tx2 := testTransaction([]rpctypes.OutPoint{rpctypes.OutPoint{TxHash: tx1.Hash}}, 100, nil)
+	tx2.Hash[0] = 3
+	block := rpctypes.BlockView{
+		Transactions: []rpctypes.TransactionView{testTransaction(nil, 50, nil), tx1, tx2},
+	}
+	err = ResolveInputs(&block, get, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Transactions[0].Inputs[0].PreviousOutput.GraphqlCell != nil {
+		t.Error("Cellbase input should not be resolved!")
+	}
+	input := block.Transactions[1].Inputs[0].PreviousOutput
+	if input.GraphqlCell.Capacity != 300 || (*input.GraphqlCellData.Content)[0] != 3 {
+		t.Errorf("Invalid stored input: %v", input)
+	}
+	input = block.Transactions[2].Inputs[0].PreviousOutput
+	if input.GraphqlCell.Capacity != 200 || (*input.GraphqlCellData.Content)[0] != 2 {
+		t.Errorf("Invalid input from same block: %v", input)
+	}
+	if len(block.Transactions[2].GraphqlCellsData) != 1 {
+		t.Error("Cells data should be filled!")
+	}
+
+	// Spending the same cell twice
+	tx3 := testTransaction([]rpctypes.OutPoint{rpctypes.OutPoint{TxHash: tx1.Hash}}, 100, nil)
+	tx3.Hash[0] = 4
+	block.Transactions = []rpctypes.TransactionView{tx1, tx2, tx3}
+	block.Transactions[1].Inputs[0].PreviousOutput.GraphqlCell = nil
+	err = ResolveInputs(&block, get, nil)
+	if err == nil {
+		t.Error("Resolving a spent cell should fail!")
+	}
+}
+
+func TestRpcPreviousOutput(t *testing.T) {
+	var blockHash rpctypes.Hash
+	blockHash[0] = 7
+	committed := testTransaction(nil, 400, []byte{4})
+	committed.Hash[0] = 1
+	pending := testTransaction(nil, 500, nil)
+	pending.Hash[0] = 2
+	server := newTestRpcServer(t, map[string]testRpcHandler{
+		"get_tip_block_number": func(params []json.RawMessage) (interface{}, *rpcError) {
+			return "0x1", nil
+		},
+		"get_transaction": func(params []json.RawMessage) (interface{}, *rpcError) {
+			var hash rpctypes.Hash
+			err := json.Unmarshal(params[0], &hash)
+			if err != nil {
+				return nil, &rpcError{Code: -32602, Message: err.Error()}
+			}
+			switch hash {
+			case committed.Hash:
+				return map[string]interface{}{
+					"transaction": committed,
+					"tx_status":   map[string]interface{}{"status": "committed", "block_hash": blockHash},
+				}, nil
+			case pending.Hash:
+				return map[string]interface{}{
+					"transaction": pending,
+					"tx_status":   map[string]interface{}{"status": "pending", "block_hash": nil},
+				}, nil
+			}
+			return nil, nil
+		},
+	})
+	defer server.Close()
+
+	s, err := NewRpcSource(server.URL, store.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cell, err := s.PreviousOutput(rpctypes.OutPoint{TxHash: committed.Hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cell == nil || cell.Output.Capacity != 400 || len(cell.Data) != 1 || cell.Data[0] != 4 ||
+		cell.BlockHash != blockHash {
+		t.Errorf("Invalid cell: %v", cell)
+	}
+	var missing rpctypes.OutPoint
+	missing.TxHash[0] = 3
+	for _, outPoint := range []rpctypes.OutPoint{
+		rpctypes.OutPoint{TxHash: committed.Hash, Index: 1},
+		rpctypes.OutPoint{TxHash: pending.Hash},
+		missing,
+	} {
+		cell, err = s.PreviousOutput(outPoint)
+		if err != nil || cell != nil {
+			t.Errorf("Cell %x:%d should not be found: %v, error: %v", outPoint.TxHash[:], outPoint.Index, cell, err)
+		}
+	}
+
+	// Inputs missing from the store are resolved from the node
+	tx := testTransaction([]rpctypes.OutPoint{rpctypes.OutPoint{TxHash: committed.Hash}}, 300, nil)
+	tx.Hash[0] = 4
+	block := rpctypes.BlockView{Transactions: []rpctypes.TransactionView{tx}}
+	err = ResolveInputs(&block, s.store.Get, s.PreviousOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := block.Transactions[0].Inputs[0].PreviousOutput
+	if input.GraphqlCell.Capacity != 400 || (*input.GraphqlCellData.Content)[0] != 4 {
+		t.Errorf("Invalid fetched input: %v", input)
+	}
+	block.Transactions[0].Inputs[0].PreviousOutput = missing
+	err = ResolveInputs(&block, s.store.Get, s.PreviousOutput)
+	if err == nil {
+		t.Error("Resolving a missing cell should fail!")
+	}
+}
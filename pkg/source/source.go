@@ -0,0 +1,23 @@
+package source
+
+import (
+	"github.com/xxuejie/animagus/pkg/rpctypes"
+)
+
+// Source provides chain data to the indexer and generic server.
+//
+// Blocks returned by a Source must have outputs data filled in
+// GraphqlCellsData, and the previous output of each input resolved via
+// GraphqlCell and GraphqlCellData, except for cellbase inputs.
+type Source interface {
+	// Block returns nil when the requested block is not available yet.
+	Block(number uint64) (*rpctypes.BlockView, error)
+	// Cells resolves live cells, with GraphqlCell, GraphqlCellData and
+	// GraphqlHeader filled. Cells that are not live are skipped.
+	Cells(outPoints []rpctypes.OutPoint) ([]*rpctypes.OutPoint, error)
+}
+
+// TipSource is implemented by sources that know the current chain tip.
+type TipSource interface {
+	TipBlockNumber() (uint64, error)
+}
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltValuesBucket = []byte("values")
+	boltSetsBucket   = []byte("sets")
+)
+
+// BoltStore keeps indexed data in a single bbolt file, each Commit or Revert
+// runs in one bbolt transaction. Published values are only delivered to
+// subscribers within the same process.
+type BoltStore struct {
+	db     *bolt.DB
+	broker *broker
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltValuesBucket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(boltSetsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{
+		db:     db,
+		broker: newBroker(),
+	}, nil
+}
+
+func (s *BoltStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltValuesBucket).Get([]byte(key))
+		if v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (s *BoltStore) Members(key string) ([][]byte, error) {
+	members := make([][]byte, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		set := tx.Bucket(boltSetsBucket).Bucket([]byte(key))
+		if set == nil {
+			return nil
+		}
+		return set.ForEach(func(k, _ []byte) error {
+			members = append(members, append([]byte{}, k...))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (s *BoltStore) Commit(batch *Batch) error {
+	var revertData []byte
+	if len(batch.RevertKey) > 0 {
+		var err error
+		revertData, err = EncodeOps(batch.RevertOps)
+		if err != nil {
+			return err
+		}
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		err := applyBoltOps(tx, batch.Ops)
+		if err != nil {
+			return err
+		}
+		if revertData != nil {
+			return tx.Bucket(boltValuesBucket).Put([]byte(batch.RevertKey), revertData)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.broker.publish(batch.Ops)
+	return nil
+}
+
+func (s *BoltStore) Revert(revertKey string) error {
+	var ops []Op
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		revertData := tx.Bucket(boltValuesBucket).Get([]byte(revertKey))
+		if revertData == nil {
+			return fmt.Errorf("Revert key %s is missing!", revertKey)
+		}
+		var err error
+		ops, err = DecodeOps(revertData)
+		if err != nil {
+			return err
+		}
+		return applyBoltOps(tx, ops)
+	})
+	if err != nil {
+		return err
+	}
+	s.broker.publish(ops)
+	return nil
+}
+
+func (s *BoltStore) DeletePrefix(prefix string) error {
+	if len(prefix) == 0 {
+		return fmt.Errorf("Deleting all keys is not allowed!")
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltValuesBucket, boltSetsBucket} {
+			bucket := tx.Bucket(name)
+			// Keys are collected first, since deleting while iterating
+			// might skip keys.
+			var keys [][]byte
+			c := bucket.Cursor()
+			for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+				keys = append(keys, append([]byte{}, k...))
+			}
+			for _, key := range keys {
+				var err error
+				if bytes.Equal(name, boltSetsBucket) {
+					err = bucket.DeleteBucket(key)
+				} else {
+					err = bucket.Delete(key)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func applyBoltOps(tx *bolt.Tx, ops []Op) error {
+	values := tx.Bucket(boltValuesBucket)
+	sets := tx.Bucket(boltSetsBucket)
+	for _, op := range ops {
+		if len(op.Key) == 0 {
+			return fmt.Errorf("Empty key for op %s!", op.Type)
+		}
+		key := []byte(op.Key)
+		var err error
+		switch op.Type {
+		case OpSet:
+			err = values.Put(key, op.Value)
+		case OpDelete:
+			err = values.Delete(key)
+			if err == nil {
+				err = sets.DeleteBucket(key)
+				if err == bolt.ErrBucketNotFound {
+					err = nil
+				}
+			}
+		case OpAdd:
+			if len(op.Value) == 0 {
+				return fmt.Errorf("Empty member for set %s!", op.Key)
+			}
+			var set *bolt.Bucket
+			set, err = sets.CreateBucketIfNotExists(key)
+			if err == nil {
+				err = set.Put(op.Value, []byte{})
+			}
+		case OpRemove:
+			set := sets.Bucket(key)
+			if set == nil || len(op.Value) == 0 {
+				continue
+			}
+			err = set.Delete(op.Value)
+			if err == nil {
+				// Empty sets are removed, so they behave like missing keys.
+				if k, _ := set.Cursor().First(); k == nil {
+					err = sets.DeleteBucket(key)
+				}
+			}
+		case OpPublish:
+		default:
+			err = fmt.Errorf("Invalid op type: %s", op.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) Subscribe(channel string) (Subscription, error) {
+	return s.broker.subscribe(channel), nil
+}
+
+func (s *BoltStore) Close() error {
+	s.broker.close()
+	return s.db.Close()
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "animagus-bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewBoltStore(filepath.Join(dir, "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	subscription, err := s.Subscribe("STREAM:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Close()
+
+	batch := &Batch{RevertKey: "REVERT"}
+	batch.Set("LAST_BLOCK", []byte{1})
+	batch.Add("CELLS", []byte{2})
+	batch.Add("CELLS", []byte{3})
+	batch.Publish("STREAM:a", []byte("insert"))
+	batch.RevertOps = []Op{
+		{Type: OpDelete, Key: "LAST_BLOCK"},
+		{Type: OpRemove, Key: "CELLS", Value: []byte{2}},
+		{Type: OpRemove, Key: "CELLS", Value: []byte{3}},
+		{Type: OpPublish, Key: "STREAM:a", Value: []byte("remove")},
+		{Type: OpDelete, Key: "REVERT"},
+	}
+	err = s.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := s.Get("LAST_BLOCK")
+	if err != nil || !bytes.Equal(value, []byte{1}) {
+		t.Fatalf("Invalid value: %x, error: %v", value, err)
+	}
+	members, err := s.Members("CELLS")
+	if err != nil || len(members) != 2 {
+		t.Fatalf("Invalid members: %x, error: %v", members, err)
+	}
+	data, err := subscription.Receive()
+	if err != nil || string(data) != "insert" {
+		t.Fatalf("Invalid published value: %s, error: %v", data, err)
+	}
+
+	err = s.Revert("REVERT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"LAST_BLOCK", "REVERT"} {
+		value, err = s.Get(key)
+		if err != nil || value != nil {
+			t.Errorf("Key %s is not reverted: %x, error: %v", key, value, err)
+		}
+	}
+	members, err = s.Members("CELLS")
+	if err != nil || len(members) != 0 {
+		t.Fatalf("Invalid members: %x, error: %v", members, err)
+	}
+	data, err = subscription.Receive()
+	if err != nil || string(data) != "remove" {
+		t.Fatalf("Invalid published value: %s, error: %v", data, err)
+	}
+	err = s.Revert("REVERT")
+	if err == nil {
+		t.Fatal("Reverting a missing revert key should fail!")
+	}
+}
+
+func TestBoltDeletePrefix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "animagus-bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewBoltStore(filepath.Join(dir, "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	checkDeletePrefix(t, s)
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore keeps indexed data in memory, it is mainly useful in tests.
+type MemoryStore struct {
+	mutex  sync.RWMutex
+	values map[string][]byte
+	sets   map[string]map[string]bool
+	broker *broker
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		values: make(map[string][]byte),
+		sets:   make(map[string]map[string]bool),
+		broker: newBroker(),
+	}
+}
+
+func (s *MemoryStore) Get(key string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, found := s.values[key]
+	if !found {
+		return nil, nil
+	}
+	return append([]byte{}, value...), nil
+}
+
+// Members returns set members in sorted order.
+func (s *MemoryStore) Members(key string) ([][]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	members := make([][]byte, 0, len(s.sets[key]))
+	for member := range s.sets[key] {
+		members = append(members, []byte(member))
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return bytes.Compare(members[i], members[j]) < 0
+	})
+	return members, nil
+}
+
+// Keys returns all keys, including set keys, in sorted order.
+func (s *MemoryStore) Keys() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := make([]string, 0, len(s.values)+len(s.sets))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	for key := range s.sets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *MemoryStore) Commit(batch *Batch) error {
+	var revertData []byte
+	if len(batch.RevertKey) > 0 {
+		var err error
+		revertData, err = EncodeOps(batch.RevertOps)
+		if err != nil {
+			return err
+		}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.validate(batch.Ops)
+	if err != nil {
+		return err
+	}
+	s.apply(batch.Ops)
+	if revertData != nil {
+		s.values[batch.RevertKey] = revertData
+	}
+	s.broker.publish(batch.Ops)
+	return nil
+}
+
+func (s *MemoryStore) Revert(revertKey string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	revertData, found := s.values[revertKey]
+	if !found {
+		return fmt.Errorf("Revert key %s is missing!", revertKey)
+	}
+	ops, err := DecodeOps(revertData)
+	if err != nil {
+		return err
+	}
+	err = s.validate(ops)
+	if err != nil {
+		return err
+	}
+	s.apply(ops)
+	s.broker.publish(ops)
+	return nil
+}
+
+func (s *MemoryStore) DeletePrefix(prefix string) error {
+	if len(prefix) == 0 {
+		return fmt.Errorf("Deleting all keys is not allowed!")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key := range s.values {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.values, key)
+		}
+	}
+	for key := range s.sets {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.sets, key)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) validate(ops []Op) error {
+	for _, op := range ops {
+		if op.Type < OpSet || op.Type > OpPublish {
+			return fmt.Errorf("Invalid op type: %s", op.Type)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) apply(ops []Op) {
+	for _, op := range ops {
+		switch op.Type {
+		case OpSet:
+			s.values[op.Key] = append([]byte{}, op.Value...)
+		case OpDelete:
+			delete(s.values, op.Key)
+			delete(s.sets, op.Key)
+		case OpAdd:
+			if s.sets[op.Key] == nil {
+				s.sets[op.Key] = make(map[string]bool)
+			}
+			s.sets[op.Key][string(op.Value)] = true
+		case OpRemove:
+			delete(s.sets[op.Key], string(op.Value))
+			if len(s.sets[op.Key]) == 0 {
+				delete(s.sets, op.Key)
+			}
+		}
+	}
+}
+
+func (s *MemoryStore) Subscribe(channel string) (Subscription, error) {
+	return s.broker.subscribe(channel), nil
+}
+
+// Subscribers returns the number of active subscriptions to channel.
+func (s *MemoryStore) Subscribers(channel string) int {
+	return s.broker.count(channel)
+}
+
+func (s *MemoryStore) Close() error {
+	s.broker.close()
+	return nil
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PrefixStore namespaces all keys and channels of an underlying store with a
+// prefix, so multiple indexers can share one store. Closing a PrefixStore
+// does not close the underlying store.
+//
+// Keys starting with one of shared prefixes are kept as they are, so they
+// can be shared among multiple PrefixStores.
+type PrefixStore struct {
+	store  Store
+	prefix string
+	shared []string
+}
+
+func NewPrefixStore(s Store, prefix string, shared ...string) *PrefixStore {
+	return &PrefixStore{
+		store:  s,
+		prefix: prefix,
+		shared: shared,
+	}
+}
+
+func (s *PrefixStore) key(key string) string {
+	for _, shared := range s.shared {
+		if strings.HasPrefix(key, shared) {
+			return key
+		}
+	}
+	return s.prefix + key
+}
+
+func (s *PrefixStore) Get(key string) ([]byte, error) {
+	return s.store.Get(s.key(key))
+}
+
+func (s *PrefixStore) Members(key string) ([][]byte, error) {
+	return s.store.Members(s.key(key))
+}
+
+func (s *PrefixStore) Commit(batch *Batch) error {
+	prefixed := &Batch{
+		Ops:       s.prefixOps(batch.Ops),
+		RevertOps: s.prefixOps(batch.RevertOps),
+	}
+	if len(batch.RevertKey) > 0 {
+		prefixed.RevertKey = s.key(batch.RevertKey)
+	}
+	return s.store.Commit(prefixed)
+}
+
+func (s *PrefixStore) prefixOps(ops []Op) []Op {
+	if ops == nil {
+		return nil
+	}
+	results := make([]Op, len(ops))
+	for i, op := range ops {
+		results[i] = Op{
+			Type:  op.Type,
+			Key:   s.key(op.Key),
+			Value: op.Value,
+		}
+	}
+	return results
+}
+
+// Revert works since revert ops are stored with prefixed keys.
+func (s *PrefixStore) Revert(revertKey string) error {
+	return s.store.Revert(s.key(revertKey))
+}
+
+func (s *PrefixStore) DeletePrefix(prefix string) error {
+	if len(prefix) == 0 {
+		return fmt.Errorf("Deleting all keys is not allowed!")
+	}
+	return s.store.DeletePrefix(s.key(prefix))
+}
+
+func (s *PrefixStore) Subscribe(channel string) (Subscription, error) {
+	return s.store.Subscribe(s.key(channel))
+}
+
+func (s *PrefixStore) Close() error {
+	return nil
+}
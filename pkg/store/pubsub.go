@@ -0,0 +1,126 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+)
+
+const subscriptionBufferSize = 1024
+
+// broker delivers published values to subscribers within the same process,
+// it is used by stores that have no pub/sub support of their own.
+type broker struct {
+	mutex       sync.Mutex
+	subscribers map[string]map[*localSubscription]bool
+}
+
+func newBroker() *broker {
+	return &broker{
+		subscribers: make(map[string]map[*localSubscription]bool),
+	}
+}
+
+func (b *broker) subscribe(channel string) *localSubscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscription := &localSubscription{
+		broker:  b,
+		channel: channel,
+		values:  make(chan []byte, subscriptionBufferSize),
+		done:    make(chan struct{}),
+	}
+	if b.subscribers[channel] == nil {
+		b.subscribers[channel] = make(map[*localSubscription]bool)
+	}
+	b.subscribers[channel][subscription] = true
+	return subscription
+}
+
+func (b *broker) unsubscribe(subscription *localSubscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscribers := b.subscribers[subscription.channel]
+	if !subscribers[subscription] {
+		return
+	}
+	delete(subscribers, subscription)
+	if len(subscribers) == 0 {
+		delete(b.subscribers, subscription.channel)
+	}
+	close(subscription.done)
+}
+
+func (b *broker) count(channel string) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.subscribers[channel])
+}
+
+func (b *broker) publish(ops []Op) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, op := range ops {
+		if op.Type != OpPublish {
+			continue
+		}
+		for subscription := range b.subscribers[op.Key] {
+			select {
+			case subscription.values <- op.Value:
+			default:
+				// Like Redis, subscribers that cannot keep up are dropped.
+				delete(b.subscribers[op.Key], subscription)
+				subscription.err = fmt.Errorf("Subscriber of %s is too slow!", op.Key)
+				close(subscription.done)
+			}
+		}
+	}
+}
+
+func (b *broker) close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for channel, subscribers := range b.subscribers {
+		for subscription := range subscribers {
+			subscription.err = fmt.Errorf("Store is closed!")
+			close(subscription.done)
+		}
+		delete(b.subscribers, channel)
+	}
+}
+
+type localSubscription struct {
+	broker  *broker
+	channel string
+	values  chan []byte
+	done    chan struct{}
+	// err is written before done is closed.
+	err error
+}
+
+func (s *localSubscription) Receive() ([]byte, error) {
+	select {
+	case value := <-s.values:
+		return value, nil
+	case <-s.done:
+	}
+	// Deliver values buffered before the subscription ends.
+	select {
+	case value := <-s.values:
+		return value, nil
+	default:
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return nil, fmt.Errorf("Subscription is closed!")
+}
+
+func (s *localSubscription) Close() error {
+	s.broker.unsubscribe(s)
+	return nil
+}
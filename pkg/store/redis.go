@@ -0,0 +1,175 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{pool: pool}
+}
+
+func (s *RedisStore) Get(key string) ([]byte, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	value, err := redis.Bytes(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (s *RedisStore) Members(key string) ([][]byte, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	return redis.ByteSlices(conn.Do("SMEMBERS", key))
+}
+
+func (s *RedisStore) Commit(batch *Batch) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	var revertData []byte
+	if len(batch.RevertKey) > 0 {
+		var err error
+		revertData, err = EncodeOps(batch.RevertOps)
+		if err != nil {
+			return err
+		}
+	}
+	conn.Send("MULTI")
+	for _, op := range batch.Ops {
+		err := sendOp(conn, op)
+		if err != nil {
+			return err
+		}
+	}
+	if revertData != nil {
+		conn.Send("SET", batch.RevertKey, revertData)
+	}
+	_, err := conn.Do("EXEC")
+	return err
+}
+
+func (s *RedisStore) Revert(revertKey string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	revertData, err := redis.Bytes(conn.Do("GET", revertKey))
+	if err == redis.ErrNil {
+		return fmt.Errorf("Revert key %s is missing!", revertKey)
+	}
+	if err != nil {
+		return err
+	}
+	ops, err := DecodeOps(revertData)
+	if err != nil {
+		return err
+	}
+	conn.Send("MULTI")
+	for _, op := range ops {
+		err = sendOp(conn, op)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+var redisPatternEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// DeletePrefix deletes keys page by page via SCAN, so Redis is not blocked
+// for long.
+func (s *RedisStore) DeletePrefix(prefix string) error {
+	if len(prefix) == 0 {
+		return fmt.Errorf("Deleting all keys is not allowed!")
+	}
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	pattern := redisPatternEscaper.Replace(prefix) + "*"
+	cursor := "0"
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 1000))
+		if err != nil {
+			return err
+		}
+		var keys []string
+		_, err = redis.Scan(values, &cursor, &keys)
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			_, err = conn.Do("DEL", redis.Args{}.AddFlat(keys)...)
+			if err != nil {
+				return err
+			}
+		}
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+func sendOp(conn redis.Conn, op Op) error {
+	switch op.Type {
+	case OpSet:
+		return conn.Send("SET", op.Key, op.Value)
+	case OpDelete:
+		return conn.Send("DEL", op.Key)
+	case OpAdd:
+		return conn.Send("SADD", op.Key, op.Value)
+	case OpRemove:
+		return conn.Send("SREM", op.Key, op.Value)
+	case OpPublish:
+		return conn.Send("PUBLISH", op.Key, op.Value)
+	}
+	return fmt.Errorf("Invalid op type: %s", op.Type)
+}
+
+func (s *RedisStore) Subscribe(channel string) (Subscription, error) {
+	psc := redis.PubSubConn{Conn: s.pool.Get()}
+	err := psc.Subscribe(channel)
+	if err != nil {
+		psc.Close()
+		return nil, err
+	}
+	return &redisSubscription{psc: psc, channel: channel}, nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.pool.Close()
+}
+
+type redisSubscription struct {
+	psc     redis.PubSubConn
+	channel string
+}
+
+func (s *redisSubscription) Receive() ([]byte, error) {
+	for {
+		switch v := s.psc.Receive().(type) {
+		case redis.Message:
+			if v.Channel == s.channel {
+				return v.Data, nil
+			}
+		case error:
+			return nil, v
+		}
+	}
+}
+
+func (s *redisSubscription) Close() error {
+	s.psc.Unsubscribe()
+	return s.psc.Close()
+}
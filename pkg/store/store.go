@@ -0,0 +1,118 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+)
+
+// Store is the storage backend used to persist indexed data. All writes go
+// through Commit so a block is either fully indexed or not indexed at all.
+type Store interface {
+	// Get returns nil when key does not exist.
+	Get(key string) ([]byte, error)
+	Members(key string) ([][]byte, error)
+	Commit(batch *Batch) error
+	// Revert atomically applies the revert ops stored at revertKey by a
+	// previous Commit.
+	Revert(revertKey string) error
+	// DeletePrefix deletes all keys starting with prefix. It is not atomic,
+	// and is meant for removing keys that are no longer used.
+	DeletePrefix(prefix string) error
+	Subscribe(channel string) (Subscription, error)
+	Close() error
+}
+
+type Subscription interface {
+	// Receive blocks till the next value published to the channel arrives.
+	Receive() ([]byte, error)
+	Close() error
+}
+
+type OpType int
+
+const (
+	OpSet OpType = iota
+	OpDelete
+	OpAdd
+	OpRemove
+	OpPublish
+)
+
+func (t OpType) String() string {
+	switch t {
+	case OpSet:
+		return "SET"
+	case OpDelete:
+		return "DELETE"
+	case OpAdd:
+		return "ADD"
+	case OpRemove:
+		return "REMOVE"
+	case OpPublish:
+		return "PUBLISH"
+	}
+	return fmt.Sprintf("OpType(%d)", int(t))
+}
+
+type Op struct {
+	Type  OpType `json:"t"`
+	Key   string `json:"k"`
+	Value []byte `json:"v,omitempty"`
+}
+
+// Batch contains ops to apply atomically, as well as ops reverting them,
+// which will be kept under RevertKey when it is not empty.
+type Batch struct {
+	Ops       []Op
+	RevertOps []Op
+	RevertKey string
+}
+
+func (b *Batch) Set(key string, value []byte) {
+	b.Ops = append(b.Ops, Op{Type: OpSet, Key: key, Value: value})
+}
+
+func (b *Batch) Delete(key string) {
+	b.Ops = append(b.Ops, Op{Type: OpDelete, Key: key})
+}
+
+func (b *Batch) Add(key string, member []byte) {
+	b.Ops = append(b.Ops, Op{Type: OpAdd, Key: key, Value: member})
+}
+
+func (b *Batch) Remove(key string, member []byte) {
+	b.Ops = append(b.Ops, Op{Type: OpRemove, Key: key, Value: member})
+}
+
+func (b *Batch) Publish(channel string, value []byte) {
+	b.Ops = append(b.Ops, Op{Type: OpPublish, Key: channel, Value: value})
+}
+
+func EncodeOps(ops []Op) ([]byte, error) {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	err := json.NewEncoder(gzipWriter).Encode(ops)
+	if err != nil {
+		return nil, err
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func DecodeOps(data []byte) ([]Op, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var ops []Op
+	err = json.NewDecoder(gzipReader).Decode(&ops)
+	if err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeOps(t *testing.T) {
+	batch := &Batch{}
+	batch.Set("LAST_BLOCK", []byte{1, 2, 3})
+	batch.Delete("BLOCK:1:HASH")
+	batch.Add("CELLS", []byte{0, 0xff})
+	batch.Remove("CELLS", []byte{})
+	batch.Publish("STREAM:a", []byte("value"))
+
+	data, err := EncodeOps(batch.Ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := DecodeOps(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != len(batch.Ops) {
+		t.Fatalf("Invalid op count: %d", len(ops))
+	}
+	for i, op := range ops {
+		if op.Type != batch.Ops[i].Type || op.Key != batch.Ops[i].Key ||
+			len(op.Value) != len(batch.Ops[i].Value) ||
+			(len(op.Value) > 0 && !reflect.DeepEqual(op.Value, batch.Ops[i].Value)) {
+			t.Errorf("Op %d mismatch: %v, expected: %v", i, op, batch.Ops[i])
+		}
+	}
+}
+
+func TestPrefixStore(t *testing.T) {
+	s := NewMemoryStore()
+	a := NewPrefixStore(s, "PROGRAM:a:")
+	b := NewPrefixStore(s, "PROGRAM:b:")
+	subscription, err := b.Subscribe("STREAM:x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Close()
+
+	for _, p := range []*PrefixStore{a, b} {
+		batch := &Batch{}
+		batch.Set("LAST_BLOCK", []byte(p.prefix))
+		batch.Add("CELLS", []byte{1})
+		batch.Publish("STREAM:x", []byte(p.prefix))
+		batch.RevertKey = "BLOCK:1:REVERT_COMMANDS"
+		batch.RevertOps = []Op{
+			Op{Type: OpDelete, Key: "LAST_BLOCK"},
+			Op{Type: OpRemove, Key: "CELLS", Value: []byte{1}},
+			Op{Type: OpDelete, Key: "BLOCK:1:REVERT_COMMANDS"},
+		}
+		err = p.Commit(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectedKeys := []string{
+		"PROGRAM:a:BLOCK:1:REVERT_COMMANDS", "PROGRAM:a:CELLS", "PROGRAM:a:LAST_BLOCK",
+		"PROGRAM:b:BLOCK:1:REVERT_COMMANDS", "PROGRAM:b:CELLS", "PROGRAM:b:LAST_BLOCK",
+	}
+	if !reflect.DeepEqual(s.Keys(), expectedKeys) {
+		t.Fatalf("Invalid keys: %v", s.Keys())
+	}
+	value, err := a.Get("LAST_BLOCK")
+	if err != nil || string(value) != "PROGRAM:a:" {
+		t.Errorf("Invalid value: %s, error: %v", value, err)
+	}
+	data, err := subscription.Receive()
+	if err != nil || string(data) != "PROGRAM:b:" {
+		t.Errorf("Invalid published value: %s, error: %v", data, err)
+	}
+
+	err = a.Revert("BLOCK:1:REVERT_COMMANDS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.Keys(), expectedKeys[3:]) {
+		t.Errorf("Invalid keys after revert: %v", s.Keys())
+	}
+	members, err := b.Members("CELLS")
+	if err != nil || len(members) != 1 {
+		t.Errorf("Invalid members: %v, error: %v", members, err)
+	}
+}
+
+func checkDeletePrefix(t *testing.T, s Store) {
+	batch := &Batch{}
+	for _, prefix := range []string{"INDEX:a:", "INDEX:b:"} {
+		batch.Set(prefix+"LAST_BLOCK", []byte{1})
+		batch.Add(prefix+"CELLS", []byte{2})
+	}
+	err := s.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.DeletePrefix("")
+	if err == nil {
+		t.Error("Deleting all keys should fail!")
+	}
+	err = s.DeletePrefix("INDEX:a:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []string{"INDEX:a:", "INDEX:b:"} {
+		value, err := s.Get(prefix + "LAST_BLOCK")
+		if err != nil {
+			t.Fatal(err)
+		}
+		members, err := s.Members(prefix + "CELLS")
+		if err != nil {
+			t.Fatal(err)
+		}
+		deleted := prefix == "INDEX:a:"
+		if (value == nil) != deleted || (len(members) == 0) != deleted {
+			t.Errorf("Invalid keys with prefix %s: %v, %v", prefix, value, members)
+		}
+	}
+}
+
+func TestMemoryDeletePrefix(t *testing.T) {
+	checkDeletePrefix(t, NewMemoryStore())
+	checkDeletePrefix(t, NewPrefixStore(NewMemoryStore(), "PROGRAM:a:"))
+}
+
+func TestPrefixStoreShared(t *testing.T) {
+	s := NewMemoryStore()
+	a := NewPrefixStore(s, "INDEX:a:", "QUERY:")
+	b := NewPrefixStore(s, "INDEX:b:", "QUERY:")
+	batch := &Batch{}
+	batch.Set("LAST_BLOCK", []byte{1})
+	batch.Add("QUERY:1:CELLS", []byte{2})
+	err := a.Commit(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.Keys(), []string{"INDEX:a:LAST_BLOCK", "QUERY:1:CELLS"}) {
+		t.Fatalf("Invalid keys: %v", s.Keys())
+	}
+	members, err := b.Members("QUERY:1:CELLS")
+	if err != nil || len(members) != 1 {
+		t.Errorf("Invalid shared members: %v, error: %v", members, err)
+	}
+	value, err := b.Get("LAST_BLOCK")
+	if err != nil || value != nil {
+		t.Errorf("Invalid value: %v, error: %v", value, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/xxuejie/animagus/pkg/ast"
+	"github.com/xxuejie/animagus/pkg/rpctypes"
 )
 
 func Verify(expr *ast.Value) error {
@@ -70,6 +71,12 @@ func Verify(expr *ast.Value) error {
 		if len(expr.GetChildren()) != 2 {
 			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
 		}
+		since := expr.GetChildren()[1]
+		if since.GetT() == ast.Value_UINT64 {
+			if err := rpctypes.VerifySince(since.GetU()); err != nil {
+				return err
+			}
+		}
 	case ast.Value_CELL_DEP:
 		if len(expr.GetChildren()) != 2 {
 			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
@@ -79,13 +86,18 @@ func Verify(expr *ast.Value) error {
 			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
 		}
 	case ast.Value_CELL:
-		if len(expr.GetChildren()) != 4 || len(expr.GetChildren()) != 6 {
+		if len(expr.GetChildren()) < 4 || len(expr.GetChildren()) > 6 {
 			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
 		}
 	case ast.Value_TRANSACTION:
-		if len(expr.GetChildren()) != 3 {
+		if len(expr.GetChildren()) < 3 || len(expr.GetChildren()) > 5 {
 			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
 		}
+		if expr.GetPrimitive() != nil {
+			if _, ok := expr.GetPrimitive().(*ast.Value_U); !ok {
+				return fmt.Errorf("TRANSACTION checks must be set in u!")
+			}
+		}
 	case ast.Value_HEADER:
 		if len(expr.GetChildren()) != 10 {
 			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
@@ -215,6 +227,7 @@ func Verify(expr *ast.Value) error {
 		case ast.Value_SCRIPT:
 		case ast.Value_HEADER:
 		case ast.Value_TRANSACTION:
+		case ast.Value_DEDUCT_FEE:
 		default:
 			return fmt.Errorf("Cannot perform %s operation on %s", expr.GetT().String(), value.GetT().String())
 		}
@@ -258,6 +271,75 @@ func Verify(expr *ast.Value) error {
 		if len(expr.GetChildren()) != 2 {
 			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
 		}
+	case ast.Value_DECODE_ADDRESS:
+		if len(expr.GetChildren()) != 1 && len(expr.GetChildren()) != 2 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_ENCODE_ADDRESS:
+		if len(expr.GetChildren()) != 2 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_DECODE_DAO:
+		fallthrough
+	case ast.Value_DECODE_EPOCH:
+		if len(expr.GetChildren()) != 1 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_DAO_MAXIMUM_WITHDRAW:
+		if len(expr.GetChildren()) != 3 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_ENCODE_SINCE:
+		if len(expr.GetChildren()) != 3 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+		if err := verifySinceArguments(expr.GetChildren()); err != nil {
+			return err
+		}
+	case ast.Value_OCCUPIED_CAPACITY:
+		fallthrough
+	case ast.Value_DECODE_SINCE:
+		if len(expr.GetChildren()) != 1 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_FEE:
+		if len(expr.GetChildren()) != 2 && len(expr.GetChildren()) != 3 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_DEDUCT_FEE:
+		if len(expr.GetChildren()) != 3 && len(expr.GetChildren()) != 4 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_SIGNING_ENTRIES:
+		fallthrough
+	case ast.Value_SIGNING_REQUEST:
+		if len(expr.GetChildren()) != 1 && len(expr.GetChildren()) != 2 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_MULTISIG_LOCK_ARGS:
+		if len(expr.GetChildren()) != 3 && len(expr.GetChildren()) != 4 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_MULTISIG_WITNESS:
+		if len(expr.GetChildren()) != 3 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_ACP_LOCK:
+		if len(expr.GetChildren()) < 2 || len(expr.GetChildren()) > 4 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_IS_ACP_LOCK:
+		if len(expr.GetChildren()) != 1 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_ACP_TOP_UP:
+		if len(expr.GetChildren()) != 2 && len(expr.GetChildren()) != 3 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
+	case ast.Value_TYPE_ID:
+		if len(expr.GetChildren()) != 2 {
+			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
+		}
 	case ast.Value_COND:
 		if len(expr.GetChildren()) != 3 {
 			return fmt.Errorf("Invalid number of arguments for %s!", expr.GetT().String())
@@ -284,6 +366,29 @@ func isList(l *ast.Value) bool {
 	return true
 }
 
+// Arguments to ENCODE_SINCE might come from params, only constant values can
+// be checked here.
+func verifySinceArguments(args []*ast.Value) error {
+	metric, relative, value := args[0], args[1], args[2]
+	if metric.GetT() == ast.Value_BOOL || metric.GetT() == ast.Value_BYTES {
+		return fmt.Errorf("Since metric must be an UINT64 value!")
+	}
+	if relative.GetT() == ast.Value_UINT64 || relative.GetT() == ast.Value_BYTES {
+		return fmt.Errorf("Since relative flag must be a BOOL value!")
+	}
+	if value.GetT() == ast.Value_BOOL || value.GetT() == ast.Value_BYTES {
+		return fmt.Errorf("Since value must be an UINT64 value!")
+	}
+	if metric.GetT() == ast.Value_UINT64 && value.GetT() == ast.Value_UINT64 {
+		if _, err := rpctypes.EncodeSince(metric.GetU(), false, value.GetU()); err != nil {
+			return err
+		}
+	} else if metric.GetT() == ast.Value_UINT64 && metric.GetU() > rpctypes.SinceMetricTimestamp {
+		return fmt.Errorf("Invalid since metric: %d", metric.GetU())
+	}
+	return nil
+}
+
 func verifyFuncArgs(f *ast.Value, args int) error {
 	remainingValues := []*ast.Value{f}
 	for len(remainingValues) > 0 {
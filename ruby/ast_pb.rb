@@ -76,6 +76,24 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       value :MULTIPLY, 87
       value :DIVIDE, 88
       value :MOD, 89
+      value :DECODE_ADDRESS, 90
+      value :ENCODE_ADDRESS, 91
+      value :DECODE_DAO, 92
+      value :DECODE_EPOCH, 93
+      value :DAO_MAXIMUM_WITHDRAW, 94
+      value :ENCODE_SINCE, 95
+      value :DECODE_SINCE, 96
+      value :OCCUPIED_CAPACITY, 97
+      value :FEE, 98
+      value :DEDUCT_FEE, 99
+      value :SIGNING_ENTRIES, 100
+      value :MULTISIG_LOCK_ARGS, 101
+      value :MULTISIG_WITNESS, 102
+      value :ACP_LOCK, 103
+      value :IS_ACP_LOCK, 104
+      value :ACP_TOP_UP, 105
+      value :TYPE_ID, 106
+      value :SIGNING_REQUEST, 107
       value :COND, 120
       value :TAIL_RECURSION, 121
     end
@@ -10,9 +10,26 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :name, :string, 1
       repeated :params, :message, 2, "ast.Value"
     end
+    add_message "generic.StatusParams" do
+      optional :program, :string, 1
+    end
+    add_message "generic.Status" do
+      optional :program, :string, 1
+      optional :ast_hash, :bytes, 2
+      optional :indexed_block_number, :uint64, 3
+      optional :indexed_block_hash, :bytes, 4
+      optional :tip_block_number, :uint64, 5
+      optional :lag, :uint64, 6
+      optional :blocks_per_second, :double, 7
+      optional :last_reorg_block_number, :uint64, 8
+      optional :last_reorg_time, :int64, 9
+      optional :tip_known, :bool, 10
+    end
   end
 end
 
 module Generic
   GenericParams = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("generic.GenericParams").msgclass
+  StatusParams = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("generic.StatusParams").msgclass
+  Status = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("generic.Status").msgclass
 end
@@ -16,6 +16,7 @@ module Generic
 
       rpc :Call, GenericParams, Ast::Value
       rpc :Stream, GenericParams, stream(Ast::Value)
+      rpc :Status, StatusParams, Status
     end
 
     Stub = Service.rpc_stub_class